@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"github.com/acj/krapslog/timefinder"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type aggregationKind int
+
+const (
+	aggregateSum aggregationKind = iota
+	aggregateAverage
+	aggregateMin
+	aggregateMax
+	aggregateQuantile
+)
+
+// aggregation is how the values of the lines in a bucket are combined into the height of the bucket's bar. It's the
+// value of the -agg flag.
+type aggregation struct {
+	kind aggregationKind
+	// quantile is between 0 and 1 for aggregateQuantile
+	quantile float64
+}
+
+func (a *aggregation) String() string {
+	switch a.kind {
+	case aggregateAverage:
+		return "avg"
+	case aggregateMin:
+		return "min"
+	case aggregateMax:
+		return "max"
+	case aggregateQuantile:
+		return "p" + strconv.FormatFloat(a.quantile*100, 'f', -1, 64)
+	default:
+		return "sum"
+	}
+}
+
+func (a *aggregation) Set(value string) error {
+	switch value {
+	case "sum":
+		*a = aggregation{kind: aggregateSum}
+	case "avg":
+		*a = aggregation{kind: aggregateAverage}
+	case "min":
+		*a = aggregation{kind: aggregateMin}
+	case "max":
+		*a = aggregation{kind: aggregateMax}
+	default:
+		percentile, err := strconv.ParseFloat(strings.TrimPrefix(value, "p"), 64)
+		if !strings.HasPrefix(value, "p") || err != nil || percentile < 0 || percentile > 100 {
+			return fmt.Errorf("must be 'sum', 'avg', 'min', 'max', or a percentile from 'p0' to 'p100' like 'p99'")
+		}
+		*a = aggregation{kind: aggregateQuantile, quantile: percentile / 100}
+	}
+	return nil
+}
+
+// valueExtractor finds the value of a line using the first capture group of a regular expression, or the whole match
+// if there isn't a capture group. For structured lines, it can use the value of a field instead.
+type valueExtractor struct {
+	re *regexp.Regexp
+	// field is the path of the field that holds the value, or empty to use re
+	field string
+}
+
+func newValueExtractor(pattern string) (*valueExtractor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &valueExtractor{re: re}, nil
+}
+
+func newFieldValueExtractor(field string) *valueExtractor {
+	return &valueExtractor{field: field}
+}
+
+// value returns the value of the line. The second return value is false if the line doesn't match (or doesn't have
+// the field) or if what matched isn't a finite number.
+func (e *valueExtractor) value(line string, fields timefinder.Fields) (float64, bool) {
+	text, ok := e.text(line, fields)
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
+
+func (e *valueExtractor) text(line string, fields timefinder.Fields) (string, bool) {
+	if e.field != "" {
+		return fields.Field(e.field)
+	}
+	match := e.re.FindStringSubmatch(line)
+	if match == nil {
+		return "", false
+	}
+	if len(match) > 1 {
+		return match[1], true
+	}
+	return match[0], true
+}
+
+// aggregate summarizes the values in a bucket. The zero value is an empty aggregate.
+type aggregate struct {
+	count int
+	sum   float64
+	min   float64
+	max   float64
+	// sketch is only used for quantiles
+	sketch *quantileSketch
+}
+
+func (a *aggregate) add(value float64, agg aggregation) {
+	if a.count == 0 || value < a.min {
+		a.min = value
+	}
+	if a.count == 0 || value > a.max {
+		a.max = value
+	}
+	a.count++
+	a.sum += value
+	if agg.kind == aggregateQuantile {
+		if a.sketch == nil {
+			a.sketch = newQuantileSketch()
+		}
+		a.sketch.add(value)
+	}
+}
+
+func (a *aggregate) merge(other *aggregate) {
+	if other.count == 0 {
+		return
+	}
+	if a.count == 0 || other.min < a.min {
+		a.min = other.min
+	}
+	if a.count == 0 || other.max > a.max {
+		a.max = other.max
+	}
+	a.count += other.count
+	a.sum += other.sum
+	if other.sketch != nil {
+		if a.sketch == nil {
+			a.sketch = newQuantileSketch()
+		}
+		a.sketch.merge(other.sketch)
+	}
+}
+
+// result combines the values. It returns 0 if there aren't any.
+func (a *aggregate) result(agg aggregation) float64 {
+	if a.count == 0 {
+		return 0
+	}
+	switch agg.kind {
+	case aggregateAverage:
+		return a.sum / float64(a.count)
+	case aggregateMin:
+		return a.min
+	case aggregateMax:
+		return a.max
+	case aggregateQuantile:
+		return a.sketch.quantile(agg.quantile)
+	default:
+		return a.sum
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_aggregationSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    aggregation
+		wantErr bool
+	}{
+		{"sum", aggregation{kind: aggregateSum}, false},
+		{"avg", aggregation{kind: aggregateAverage}, false},
+		{"min", aggregation{kind: aggregateMin}, false},
+		{"max", aggregation{kind: aggregateMax}, false},
+		{"p50", aggregation{kind: aggregateQuantile, quantile: 0.5}, false},
+		{"p99.9", aggregation{kind: aggregateQuantile, quantile: 0.999}, false},
+		{"p0", aggregation{kind: aggregateQuantile, quantile: 0}, false},
+		{"p100", aggregation{kind: aggregateQuantile, quantile: 1}, false},
+		{"p101", aggregation{}, true},
+		{"99", aggregation{}, true},
+		{"median", aggregation{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var got aggregation
+			err := got.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if math.Abs(got.quantile-tt.want.quantile) > 1e-9 || got.kind != tt.want.kind {
+				t.Errorf("Set() = %+v, want %+v", got, tt.want)
+			}
+			if got.String() != tt.value {
+				t.Errorf("String() = %v, want %v", got.String(), tt.value)
+			}
+		})
+	}
+}
+
+func Test_valueExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		line    string
+		want    float64
+		wantOk  bool
+	}{
+		{"capture group", `\d+/\d+/\d+/\d+/(\d+) `, "public myapp/i-05fa 0/0/0/78/80 206", 80, true},
+		{"whole match", `-?\d+\.\d+`, "took 12.5ms", 12.5, true},
+		{"negative", `offset=(\S+)`, "offset=-3e2", -300, true},
+		{"no match", `took (\d+)ms`, "took a while", 0, false},
+		{"not a number", `status=(\S+)`, "status=ok", 0, false},
+		{"not finite", `value=(\S+)`, "value=NaN", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, err := newValueExtractor(tt.pattern)
+			if err != nil {
+				t.Fatalf("newValueExtractor() error = %v", err)
+			}
+			got, ok := extractor.value(tt.line, nil)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("value() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_aggregate(t *testing.T) {
+	values := []float64{4, -2, 10, 8}
+	tests := []struct {
+		agg  string
+		want float64
+	}{
+		{"sum", 20},
+		{"avg", 5},
+		{"min", -2},
+		{"max", 10},
+		{"p0", -2},
+		{"p100", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.agg, func(t *testing.T) {
+			var agg aggregation
+			if err := agg.Set(tt.agg); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+
+			// Merging the aggregates of two halves gives the same result as adding every value to one
+			var first, second aggregate
+			for i, value := range values {
+				if i < 2 {
+					first.add(value, agg)
+				} else {
+					second.add(value, agg)
+				}
+			}
+			var merged aggregate
+			merged.merge(&first)
+			merged.merge(&second)
+
+			if got := merged.result(agg); math.Abs(got-tt.want) > math.Abs(tt.want)*sketchRelativeAccuracy {
+				t.Errorf("result() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var empty aggregate
+	if got := empty.result(aggregation{kind: aggregateMax}); got != 0 {
+		t.Errorf("result() of empty aggregate = %v, want 0", got)
+	}
+}
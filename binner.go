@@ -1,25 +1,549 @@
 package main
 
+import (
+	"math"
+	"time"
+)
+
+// binner counts timestamps into a fixed number of equally sized buckets that together span the range from firstTime
+// to lastTime. Timestamps are in nanoseconds since the Unix epoch.
+type binner struct {
+	firstTime int64
+	spread    int64
+	buckets   []float64
+}
+
+func newBinner(firstTime, lastTime int64, bucketCount int) *binner {
+	return &binner{
+		firstTime: firstTime,
+		spread:    lastTime - firstTime + 1,
+		buckets:   make([]float64, bucketCount, bucketCount),
+	}
+}
+
+func (b *binner) add(lineUnixNanos int64) {
+	b.addCount(lineUnixNanos, 1)
+}
+
+// addCount adds count lines with the same timestamp.
+func (b *binner) addCount(lineUnixNanos int64, count float64) {
+	if bucket, ok := b.bucket(lineUnixNanos); ok {
+		b.buckets[bucket] += count
+	}
+}
+
+// bucket returns the index of the bucket that lineUnixNanos falls in. The second return value is false if it's
+// outside the range of the buckets.
+func (b *binner) bucket(lineUnixNanos int64) (int, bool) {
+	if lineUnixNanos < b.firstTime || lineUnixNanos-b.firstTime >= b.spread {
+		return 0, false
+	}
+	return int((float64(len(b.buckets)) * float64(lineUnixNanos-b.firstTime)) / float64(b.spread)), true
+}
+
+// binTimestamps counts the timestamps into bucketCount equally sized buckets that span the range from the earliest
+// timestamp to the latest one. The timestamps don't need to be in order.
 func binTimestamps(timesFromLines []int64, bucketCount int) []float64 {
-	linesPerBucket := make([]float64, bucketCount, bucketCount)
+	if len(timesFromLines) == 0 {
+		return make([]float64, bucketCount, bucketCount)
+	}
+
+	firstTime, lastTime := timestampBounds(timesFromLines)
+	return binTimestampsBetween(timesFromLines, firstTime, lastTime, bucketCount)
+}
+
+// timestampBounds returns the earliest and latest of the timestamps, which must not be empty.
+func timestampBounds(timesFromLines []int64) (int64, int64) {
+	firstTime, lastTime := timesFromLines[0], timesFromLines[0]
+	for _, lineUnixNanos := range timesFromLines[1:] {
+		if lineUnixNanos < firstTime {
+			firstTime = lineUnixNanos
+		}
+		if lineUnixNanos > lastTime {
+			lastTime = lineUnixNanos
+		}
+	}
+	return firstTime, lastTime
+}
+
+// binTimestampsBetween is like binTimestamps, but the buckets span the range from firstTime to lastTime instead of the
+// range of the timestamps themselves. Timestamps outside the range are ignored.
+func binTimestampsBetween(timesFromLines []int64, firstTime, lastTime int64, bucketCount int) []float64 {
+	b := newBinner(firstTime, lastTime, bucketCount)
+	for _, lineUnixNanos := range timesFromLines {
+		b.add(lineUnixNanos)
+	}
+	return b.buckets
+}
+
+// streamingResolution is how many of a streamingBinner's internal buckets there are for each bucket that it returns.
+const streamingResolution = 64
+
+// streamingBinner counts timestamps into buckets like binTimestamps does, but without storing every timestamp, so its
+// memory use depends on the number of buckets rather than the number of lines. It keeps the first timestamps that it's
+// given, and if there turn out to be too many of them to keep, it switches to counting them in a histogram of much
+// finer buckets whose width doubles, merging neighboring buckets, whenever a timestamp falls outside of the range that
+// they cover. The fine buckets are then combined into the final buckets, which places each line within 1/32 of a
+// bucket of where binTimestamps would.
+//
+// A streamingBinner can also aggregate a value from each line, in which case the values are kept alongside the
+// timestamps and then combined into an aggregate for each of the fine buckets.
+//
+// Alternatively, a streamingBinner can count lines into buckets of a fixed duration from the start, so that the
+// buckets it returns line up with those of another log (see withFixedBuckets).
+type streamingBinner struct {
+	bucketCount int
+	count       int
+	firstTime   int64
+	lastTime    int64
+	// aggregation is how the values of the lines are combined, or nil if the binner only counts lines
+	aggregation *aggregation
+	// valueCount is the number of lines that had a value
+	valueCount int
+	// timestamps holds every timestamp until there are more than len(fineBuckets) of them
+	timestamps []int64
+	// values holds the value of the line of each timestamp, or NaN if the line didn't have one. It's only used if the
+	// binner aggregates values.
+	values []float64
+	// fineBuckets counts the timestamps once they're no longer stored. Bucket i covers the fineBucketWidth nanoseconds
+	// starting at origin + i*fineBucketWidth, and origin is always a multiple of fineBucketWidth.
+	fineBuckets     []float64
+	fineBucketWidth int64
+	origin          int64
+	// fineValues aggregates the values in each of the fine buckets if the binner aggregates values
+	fineValues []aggregate
+	// grid divides time into the fixed buckets, or is nil if the binner doesn't use them. Only the buckets that have lines
+	// in them are stored, so a stray timestamp far from the others doesn't take up any more memory than a nearby one.
+	grid         *bucketGrid
+	fixedBuckets map[int64]float64
+	// fixedValues aggregates the values in each of the fixed buckets if the binner aggregates values
+	fixedValues map[int64]*aggregate
+}
+
+func newStreamingBinner(bucketCount int) *streamingBinner {
+	return &streamingBinner{
+		bucketCount: bucketCount,
+		timestamps:  make([]int64, 0),
+	}
+}
+
+// newValueBinner creates a streamingBinner that aggregates the values of the lines as well as counting them.
+func newValueBinner(bucketCount int, agg aggregation) *streamingBinner {
+	b := newStreamingBinner(bucketCount)
+	b.aggregation = &agg
+	b.values = make([]float64, 0)
+	return b
+}
+
+// withFixedBuckets makes b count lines into the fixed buckets of grid. It must be called before any timestamps are
+// added. It returns b.
+func (b *streamingBinner) withFixedBuckets(grid bucketGrid) *streamingBinner {
+	b.grid = &grid
+	b.fixedBuckets = make(map[int64]float64)
+	if b.aggregation != nil {
+		b.fixedValues = make(map[int64]*aggregate)
+	}
+	return b
+}
 
-	switch len(timesFromLines) {
-	case 0:
-		return linesPerBucket
-	case 1:
-		linesPerBucket[0] = 1
-		return linesPerBucket
+// empty returns a binner without any timestamps that has the same number of buckets as b, aggregates values if b
+// does, and uses the same fixed buckets as b if it has them.
+func (b *streamingBinner) empty() *streamingBinner {
+	e := newStreamingBinner(b.bucketCount)
+	if b.aggregation != nil {
+		e = newValueBinner(b.bucketCount, *b.aggregation)
+	}
+	if b.grid != nil {
+		e.withFixedBuckets(*b.grid)
 	}
+	return e
+}
+
+// keepsTimestamps reports whether b still has every timestamp that it was given.
+func (b *streamingBinner) keepsTimestamps() bool {
+	return b.fineBuckets == nil && b.grid == nil
+}
 
-	firstTime := timesFromLines[0]
-	lastTime := timesFromLines[len(timesFromLines)-1]
-	spread := lastTime - firstTime + 1
-	for _, lineUnixTime := range timesFromLines {
-		if lineUnixTime < firstTime {
+// fixedBins returns the number of lines in each of the fixed buckets from firstBucket to lastBucket. It must only be
+// called if the binner uses fixed buckets.
+func (b *streamingBinner) fixedBins(firstBucket, lastBucket int64) []float64 {
+	bins := make([]float64, lastBucket-firstBucket+1)
+	for i := range bins {
+		bins[i] = b.fixedBuckets[firstBucket+int64(i)]
+	}
+	return bins
+}
+
+// fixedLevels is like levels, but it returns the heights of the fixed buckets from firstBucket to lastBucket.
+func (b *streamingBinner) fixedLevels(firstBucket, lastBucket int64) []float64 {
+	if b.aggregation == nil {
+		return b.fixedBins(firstBucket, lastBucket)
+	}
+	levels := make([]float64, lastBucket-firstBucket+1)
+	for i := range levels {
+		if values := b.fixedValues[firstBucket+int64(i)]; values != nil {
+			levels[i] = values.result(*b.aggregation)
+		}
+	}
+	return levels
+}
+
+func (b *streamingBinner) add(lineUnixNanos int64) {
+	b.addWithValue(lineUnixNanos, math.NaN())
+}
+
+// addWithValue adds the timestamp of a line whose value is value, or NaN if it doesn't have one. The value is ignored
+// unless the binner aggregates values.
+func (b *streamingBinner) addWithValue(lineUnixNanos int64, value float64) {
+	if b.count == 0 || lineUnixNanos < b.firstTime {
+		b.firstTime = lineUnixNanos
+	}
+	if b.count == 0 || lineUnixNanos > b.lastTime {
+		b.lastTime = lineUnixNanos
+	}
+	b.count++
+	if b.aggregation == nil {
+		value = math.NaN()
+	} else if !math.IsNaN(value) {
+		b.valueCount++
+	}
+
+	if b.grid != nil {
+		b.addToFixedBucket(b.grid.bucket(lineUnixNanos), value)
+		return
+	}
+	if b.fineBuckets == nil {
+		b.timestamps = append(b.timestamps, lineUnixNanos)
+		if b.aggregation != nil {
+			b.values = append(b.values, value)
+		}
+		if len(b.timestamps) > b.bucketCount*streamingResolution {
+			b.switchToHistogram()
+		}
+		return
+	}
+
+	b.addToHistogram(lineUnixNanos, value)
+}
+
+// bounds returns the earliest and latest timestamps. It must not be called before any timestamps have been added.
+func (b *streamingBinner) bounds() (int64, int64) {
+	return b.firstTime, b.lastTime
+}
+
+// bins returns the bucket counts for buckets that span the range from firstTime to lastTime. Timestamps outside the
+// range are ignored.
+func (b *streamingBinner) bins(firstTime, lastTime int64) []float64 {
+	return b.binsOfCount(firstTime, lastTime, b.bucketCount)
+}
+
+// binsOfCount is like bins, but it returns bucketCount buckets. To keep the same accuracy, there shouldn't be more
+// buckets than the binner was created with.
+func (b *streamingBinner) binsOfCount(firstTime, lastTime int64, bucketCount int) []float64 {
+	if b.keepsTimestamps() {
+		return binTimestampsBetween(b.timestamps, firstTime, lastTime, bucketCount)
+	}
+
+	coarse := newBinner(firstTime, lastTime, bucketCount)
+	b.forEachFineBucket(func(count float64, values *aggregate, midpoint int64) {
+		coarse.addCount(midpoint, count)
+	})
+	return coarse.buckets
+}
+
+// levels returns the heights of the bars of a sparkline with bucketCount buckets that span the range from firstTime
+// to lastTime: the number of lines in each bucket, or the aggregate of their values if the binner aggregates values.
+// Buckets without any values are 0.
+func (b *streamingBinner) levels(firstTime, lastTime int64, bucketCount int) []float64 {
+	if b.aggregation == nil {
+		return b.binsOfCount(firstTime, lastTime, bucketCount)
+	}
+
+	coarse := newBinner(firstTime, lastTime, bucketCount)
+	aggregates := make([]aggregate, bucketCount)
+	if b.keepsTimestamps() {
+		for i, lineUnixNanos := range b.timestamps {
+			if bucket, ok := coarse.bucket(lineUnixNanos); ok && !math.IsNaN(b.values[i]) {
+				aggregates[bucket].add(b.values[i], *b.aggregation)
+			}
+		}
+	} else {
+		b.forEachFineBucket(func(count float64, values *aggregate, midpoint int64) {
+			if bucket, ok := coarse.bucket(midpoint); ok && values != nil {
+				aggregates[bucket].merge(values)
+			}
+		})
+	}
+
+	levels := make([]float64, bucketCount)
+	for i := range aggregates {
+		levels[i] = aggregates[i].result(*b.aggregation)
+	}
+	return levels
+}
+
+// forEachFineBucket calls fineBucketFunc with the number of lines in each fine (or fixed) bucket that has any, the
+// aggregate of their values (or nil if the binner doesn't aggregate values), and the time that the bucket's lines are
+// placed at: its midpoint, but not beyond the lines that it actually holds.
+func (b *streamingBinner) forEachFineBucket(fineBucketFunc func(count float64, values *aggregate, midpoint int64)) {
+	midpoint := func(start, width int64) int64 {
+		return min(max(start+width/2, b.firstTime), b.lastTime)
+	}
+	if b.grid != nil {
+		for bucket, count := range b.fixedBuckets {
+			start := b.grid.start(bucket)
+			fineBucketFunc(count, b.fixedValues[bucket], midpoint(start, b.grid.start(bucket+1)-start))
+		}
+		return
+	}
+	for i, count := range b.fineBuckets {
+		if count == 0 {
 			continue
 		}
-		bucket := int64((float64(bucketCount) * float64(lineUnixTime-firstTime)) / float64(spread))
-		linesPerBucket[bucket]++
+		var values *aggregate
+		if b.aggregation != nil {
+			values = &b.fineValues[i]
+		}
+		fineBucketFunc(count, values, midpoint(b.origin+int64(i)*b.fineBucketWidth, b.fineBucketWidth))
+	}
+}
+
+// merge adds the timestamps that were counted by other, which must have the same number of buckets (or the same fixed
+// buckets). Once either of them has switched to a histogram, the result is a histogram whose buckets are at least as
+// wide as other's.
+func (b *streamingBinner) merge(other *streamingBinner) {
+	if other.grid != nil {
+		b.mergeFixedBuckets(other)
+		return
+	}
+	if other.fineBuckets == nil {
+		for i, lineUnixNanos := range other.timestamps {
+			b.addWithValue(lineUnixNanos, other.valueAt(i))
+		}
+		return
+	}
+
+	if b.count == 0 || other.firstTime < b.firstTime {
+		b.firstTime = other.firstTime
+	}
+	if b.count == 0 || other.lastTime > b.lastTime {
+		b.lastTime = other.lastTime
+	}
+	b.count += other.count
+	b.valueCount += other.valueCount
+
+	if b.fineBuckets == nil {
+		b.switchToHistogram()
+	}
+	for b.fineBucketWidth < other.fineBucketWidth {
+		b.widenHistogram(b.origin)
+	}
+	// Both widths are powers of two and both origins are aligned to their widths, so each of other's buckets fits
+	// entirely within one of b's
+	for i, count := range other.fineBuckets {
+		if count != 0 {
+			bucket := b.histogramBucket(other.origin + int64(i)*other.fineBucketWidth)
+			b.fineBuckets[bucket] += count
+			if b.aggregation != nil && other.aggregation != nil {
+				b.fineValues[bucket].merge(&other.fineValues[i])
+			}
+		}
+	}
+}
+
+// valueAt returns the value of the line of the stored timestamp at index i, or NaN if it doesn't have one.
+func (b *streamingBinner) valueAt(i int) float64 {
+	if b.values == nil {
+		return math.NaN()
+	}
+	return b.values[i]
+}
+
+// mergeFixedBuckets adds the lines that were counted by other, which must use the same fixed buckets as b.
+func (b *streamingBinner) mergeFixedBuckets(other *streamingBinner) {
+	if other.count == 0 {
+		return
+	}
+	if b.count == 0 || other.firstTime < b.firstTime {
+		b.firstTime = other.firstTime
+	}
+	if b.count == 0 || other.lastTime > b.lastTime {
+		b.lastTime = other.lastTime
+	}
+	b.count += other.count
+	b.valueCount += other.valueCount
+	for bucket, count := range other.fixedBuckets {
+		b.fixedBuckets[bucket] += count
+		if values := other.fixedValues[bucket]; values != nil && b.aggregation != nil {
+			b.fixedValue(bucket).merge(values)
+		}
+	}
+}
+
+// addToFixedBucket counts a line in a fixed bucket, along with its value if it isn't NaN.
+func (b *streamingBinner) addToFixedBucket(bucket int64, value float64) {
+	b.fixedBuckets[bucket]++
+	if !math.IsNaN(value) {
+		b.fixedValue(bucket).add(value, *b.aggregation)
+	}
+}
+
+// fixedValue returns the aggregate of the values in a fixed bucket, creating it if it doesn't exist yet.
+func (b *streamingBinner) fixedValue(bucket int64) *aggregate {
+	values := b.fixedValues[bucket]
+	if values == nil {
+		values = &aggregate{}
+		b.fixedValues[bucket] = values
+	}
+	return values
+}
+
+func (b *streamingBinner) switchToHistogram() {
+	b.fineBuckets = make([]float64, b.bucketCount*streamingResolution)
+	if b.aggregation != nil {
+		b.fineValues = make([]aggregate, len(b.fineBuckets))
+	}
+	b.fineBucketWidth = 1
+	for int64(len(b.fineBuckets))*b.fineBucketWidth < 2*(b.lastTime-b.firstTime+1) {
+		b.fineBucketWidth *= 2
+	}
+	b.origin = floorDiv(b.firstTime, b.fineBucketWidth) * b.fineBucketWidth
+
+	for i, lineUnixNanos := range b.timestamps {
+		b.addToHistogram(lineUnixNanos, b.valueAt(i))
+	}
+	b.timestamps, b.values = nil, nil
+}
+
+// addToHistogram counts a line in the fine buckets, along with its value if it isn't NaN.
+func (b *streamingBinner) addToHistogram(lineUnixNanos int64, value float64) {
+	bucket := b.histogramBucket(lineUnixNanos)
+	b.fineBuckets[bucket]++
+	if !math.IsNaN(value) {
+		b.fineValues[bucket].add(value, *b.aggregation)
+	}
+}
+
+// histogramBucket returns the index of the fine bucket that lineUnixNanos falls in, widening the fine buckets first if
+// they don't cover it.
+func (b *streamingBinner) histogramBucket(lineUnixNanos int64) int64 {
+	bucket := floorDiv(lineUnixNanos-b.origin, b.fineBucketWidth)
+	if bucket < 0 || bucket >= int64(len(b.fineBuckets)) {
+		b.widenHistogram(lineUnixNanos)
+		bucket = floorDiv(lineUnixNanos-b.origin, b.fineBucketWidth)
+	}
+	return bucket
+}
+
+// widenHistogram doubles the width of the fine buckets as many times as it takes to cover lineUnixNanos as well as the
+// timestamps that have already been counted.
+func (b *streamingBinner) widenHistogram(lineUnixNanos int64) {
+	first, last := b.origin, b.origin+int64(len(b.fineBuckets))*b.fineBucketWidth-1
+	if lineUnixNanos < first {
+		first = lineUnixNanos
+	}
+	if lineUnixNanos > last {
+		last = lineUnixNanos
+	}
+
+	width, origin := b.fineBucketWidth, b.origin
+	for {
+		width *= 2
+		origin = floorDiv(first, width) * width
+		if last-origin < int64(len(b.fineBuckets))*width {
+			break
+		}
+	}
+
+	// The new width is a multiple of the old one and both origins are aligned to the old width, so each old bucket
+	// fits entirely within a new one
+	widened := make([]float64, len(b.fineBuckets))
+	var widenedValues []aggregate
+	if b.aggregation != nil {
+		widenedValues = make([]aggregate, len(b.fineBuckets))
+	}
+	for i, count := range b.fineBuckets {
+		start := b.origin + int64(i)*b.fineBucketWidth
+		bucket := floorDiv(start-origin, width)
+		widened[bucket] += count
+		if b.aggregation != nil {
+			widenedValues[bucket].merge(&b.fineValues[i])
+		}
+	}
+	b.fineBuckets, b.fineBucketWidth, b.origin = widened, width, origin
+	b.fineValues = widenedValues
+}
+
+// slidingBinner counts timestamps into buckets that cover a window of time ending with the most recent timestamp. As
+// newer timestamps arrive, the window slides forward and the oldest buckets are discarded.
+type slidingBinner struct {
+	bucketDuration int64
+	buckets        []float64
+	newestBucket   int64
+	empty          bool
+}
+
+// newSlidingBinner creates a slidingBinner that divides the window into bucketCount buckets. Buckets are never shorter
+// than one nanosecond, so the window may be stretched if it's shorter than bucketCount nanoseconds.
+func newSlidingBinner(window time.Duration, bucketCount int) *slidingBinner {
+	bucketDuration := int64(window) / int64(bucketCount)
+	if bucketDuration < 1 {
+		bucketDuration = 1
 	}
-	return linesPerBucket
+	return &slidingBinner{
+		bucketDuration: bucketDuration,
+		buckets:        make([]float64, bucketCount, bucketCount),
+		empty:          true,
+	}
+}
+
+func (b *slidingBinner) add(lineUnixNanos int64) {
+	bucket := floorDiv(lineUnixNanos, b.bucketDuration)
+	bucketCount := int64(len(b.buckets))
+
+	if b.empty {
+		b.newestBucket = bucket
+		b.empty = false
+	}
+
+	if bucket > b.newestBucket {
+		// Slide the window forward, clearing the buckets that are being reused
+		for i := b.newestBucket + 1; i <= bucket && i <= b.newestBucket+bucketCount; i++ {
+			b.buckets[floorMod(i, bucketCount)] = 0
+		}
+		b.newestBucket = bucket
+	} else if bucket <= b.newestBucket-bucketCount {
+		// Too old to fit in the window
+		return
+	}
+
+	b.buckets[floorMod(bucket, bucketCount)]++
+}
+
+// bins returns the bucket counts in chronological order.
+func (b *slidingBinner) bins() []float64 {
+	bucketCount := int64(len(b.buckets))
+	bins := make([]float64, 0, bucketCount)
+	for i := b.newestBucket - bucketCount + 1; i <= b.newestBucket; i++ {
+		bins = append(bins, b.buckets[floorMod(i, bucketCount)])
+	}
+	return bins
+}
+
+// window returns the first and last nanoseconds covered by the buckets.
+func (b *slidingBinner) window() (int64, int64) {
+	bucketCount := int64(len(b.buckets))
+	return (b.newestBucket - bucketCount + 1) * b.bucketDuration, (b.newestBucket+1)*b.bucketDuration - 1
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int64) int64 {
+	return a - floorDiv(a, b)*b
 }
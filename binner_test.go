@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"github.com/acj/krapslog/internal/test"
+	"math"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_BinTimestampsToFitLineWidth(t *testing.T) {
@@ -37,6 +40,20 @@ func Test_BinTimestampsToFitLineWidth(t *testing.T) {
 			},
 			want: []float64{1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0},
 		},
+		{
+			name: "out of order timestamps", args: args{
+				timestampsFromLines: []int64{5, 9, 1, 10, 2},
+				terminalWidth:       5,
+			},
+			want: []float64{2, 0, 1, 0, 2},
+		},
+		{
+			name: "identical timestamps", args: args{
+				timestampsFromLines: []int64{7, 7, 7},
+				terminalWidth:       5,
+			},
+			want: []float64{3, 0, 0, 0, 0},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -46,3 +63,252 @@ func Test_BinTimestampsToFitLineWidth(t *testing.T) {
 		})
 	}
 }
+
+func Test_slidingBinner(t *testing.T) {
+	sec := func(seconds int64) int64 {
+		return seconds * int64(time.Second)
+	}
+
+	t.Run("counts timestamps within the window", func(t *testing.T) {
+		b := newSlidingBinner(4*time.Second, 4)
+		for _, timestamp := range []int64{sec(10), sec(11), sec(11), sec(13)} {
+			b.add(timestamp)
+		}
+
+		if got, want := b.bins(), []float64{1, 2, 0, 1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("bins() = %v, want %v", got, want)
+		}
+		if first, last := b.window(); first != sec(10) || last != sec(14)-1 {
+			t.Errorf("window() = (%d, %d), want (%d, %d)", first, last, sec(10), sec(14)-1)
+		}
+	})
+
+	t.Run("slides forward and discards old buckets", func(t *testing.T) {
+		b := newSlidingBinner(4*time.Second, 4)
+		for _, timestamp := range []int64{sec(10), sec(11), sec(11), sec(13), sec(15), sec(14)} {
+			b.add(timestamp)
+		}
+
+		if got, want := b.bins(), []float64{0, 1, 1, 1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("bins() = %v, want %v", got, want)
+		}
+		if first, last := b.window(); first != sec(12) || last != sec(16)-1 {
+			t.Errorf("window() = (%d, %d), want (%d, %d)", first, last, sec(12), sec(16)-1)
+		}
+	})
+
+	t.Run("after a gap longer than the window, only the newest timestamp remains", func(t *testing.T) {
+		b := newSlidingBinner(4*time.Second, 4)
+		for _, timestamp := range []int64{sec(10), sec(11), sec(100)} {
+			b.add(timestamp)
+		}
+
+		if got, want := b.bins(), []float64{0, 0, 0, 1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("bins() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ignores timestamps that are older than the window", func(t *testing.T) {
+		b := newSlidingBinner(4*time.Second, 4)
+		for _, timestamp := range []int64{sec(20), sec(10), sec(17)} {
+			b.add(timestamp)
+		}
+
+		if got, want := b.bins(), []float64{1, 0, 0, 1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("bins() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("groups timestamps when the window is longer than the bucket count", func(t *testing.T) {
+		b := newSlidingBinner(8*time.Second, 4)
+		for _, timestamp := range []int64{sec(10), sec(11), sec(12), sec(17)} {
+			b.add(timestamp)
+		}
+
+		if got, want := b.bins(), []float64{2, 1, 0, 1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("bins() = %v, want %v", got, want)
+		}
+		if first, last := b.window(); first != sec(10) || last != sec(18)-1 {
+			t.Errorf("window() = (%d, %d), want (%d, %d)", first, last, sec(10), sec(18)-1)
+		}
+	})
+}
+
+func Test_streamingBinner(t *testing.T) {
+	t.Run("matches binTimestamps while the timestamps are kept", func(t *testing.T) {
+		timestamps := []int64{5, 9, 1, 10, 2, 7, 7}
+		b := newStreamingBinner(5)
+		for _, timestamp := range timestamps {
+			b.add(timestamp)
+		}
+		first, last := b.bounds()
+		if first != 1 || last != 10 {
+			t.Errorf("bounds() = %v, %v, want 1, 10", first, last)
+		}
+		if got, want := b.bins(first, last), binTimestamps(timestamps, 5); !reflect.DeepEqual(got, want) {
+			t.Errorf("bins() = %v, want %v", got, want)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		reverse bool
+	}{
+		{"in order", false},
+		{"in reverse order", true},
+	}
+	for _, tt := range tests {
+		t.Run("counts clusters of timestamps in a histogram when there are too many to keep, "+tt.name, func(t *testing.T) {
+			// Four buckets, each 1000ns wide, with most of the lines in a cluster around the middle of each bucket. The
+			// clusters are far enough from the edges of the buckets that the histogram can't place lines in the wrong
+			// bucket.
+			var timestamps []int64
+			for bucket, linesPerNanosecond := range []int{10, 0, 3, 7} {
+				for offset := int64(400); offset < 600; offset++ {
+					for i := 0; i < linesPerNanosecond; i++ {
+						timestamps = append(timestamps, int64(bucket)*1000+offset)
+					}
+				}
+			}
+			timestamps = append(timestamps, 0, 3999)
+			if tt.reverse {
+				for i, j := 0, len(timestamps)-1; i < j; i, j = i+1, j-1 {
+					timestamps[i], timestamps[j] = timestamps[j], timestamps[i]
+				}
+			}
+
+			b := newStreamingBinner(4)
+			for _, timestamp := range timestamps {
+				b.add(timestamp)
+			}
+			if b.fineBuckets == nil {
+				t.Fatalf("expected the timestamps to be counted in a histogram")
+			}
+			if len(b.fineBuckets) != 4*streamingResolution {
+				t.Errorf("len(fineBuckets) = %v, want %v", len(b.fineBuckets), 4*streamingResolution)
+			}
+			if got, want := b.bins(b.bounds()), binTimestamps(timestamps, 4); !reflect.DeepEqual(got, want) {
+				t.Errorf("bins() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_streamingBinnerValues(t *testing.T) {
+	// Four buckets, each 1000ns wide, with clusters of lines in the middle of the buckets like in Test_streamingBinner.
+	// The lines in bucket k have values from 10(k+1) to 10(k+1)+2, the second bucket is empty, and the lines at the
+	// edges don't have values.
+	type line struct {
+		timestamp int64
+		value     float64
+	}
+	lines := []line{{0, math.NaN()}, {3999, math.NaN()}}
+	for bucket := int64(0); bucket < 4; bucket++ {
+		if bucket == 1 {
+			continue
+		}
+		for offset := int64(400); offset < 600; offset++ {
+			lines = append(lines, line{bucket*1000 + offset, float64(10*(bucket+1) + offset%3)})
+		}
+	}
+
+	tests := []struct {
+		name string
+		agg  string
+		want []float64
+	}{
+		{"min", "min", []float64{10, 0, 30, 40}},
+		{"max", "max", []float64{12, 0, 32, 42}},
+		{"median", "p50", []float64{11, 0, 31, 41}},
+	}
+	for _, tt := range tests {
+		// With 4 buckets, there are too many lines to keep, so they're counted in a histogram
+		for _, bucketCount := range []int{4, 40} {
+			t.Run(fmt.Sprintf("%s with %d buckets", tt.name, bucketCount), func(t *testing.T) {
+				var agg aggregation
+				if err := agg.Set(tt.agg); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+				// Half of the lines are counted by another binner and merged in
+				b, other := newValueBinner(bucketCount, agg), newValueBinner(bucketCount, agg)
+				for i, l := range lines {
+					if i%2 == 0 {
+						b.addWithValue(l.timestamp, l.value)
+					} else {
+						other.addWithValue(l.timestamp, l.value)
+					}
+				}
+				b.merge(other)
+
+				if (b.fineBuckets != nil) != (bucketCount == 4) {
+					t.Fatalf("expected the timestamps to be counted in a histogram only when there are too many to keep")
+				}
+				if b.valueCount != len(lines)-2 {
+					t.Errorf("valueCount = %v, want %v", b.valueCount, len(lines)-2)
+				}
+				got := b.levels(0, 3999, 4)
+				for i := range got {
+					if math.Abs(got[i]-tt.want[i]) > tt.want[i]*sketchRelativeAccuracy {
+						t.Errorf("levels() = %v, want %v", got, tt.want)
+						break
+					}
+				}
+			})
+		}
+	}
+
+	t.Run("sum of ones matches the counts", func(t *testing.T) {
+		b := newValueBinner(4, aggregation{kind: aggregateSum})
+		for _, l := range lines {
+			b.addWithValue(l.timestamp, 1)
+		}
+		if got, want := b.levels(0, 3999, 4), b.bins(0, 3999); !reflect.DeepEqual(got, want) {
+			t.Errorf("levels() = %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_streamingBinnerFixedBuckets(t *testing.T) {
+	// Buckets are 1000ns wide and start at multiples of 1000ns
+	grid := bucketGrid{duration: 1000, location: time.UTC}
+	newFixedBinner := func() *streamingBinner {
+		return newValueBinner(4, aggregation{kind: aggregateMax}).withFixedBuckets(grid)
+	}
+	b, other := newFixedBinner(), newFixedBinner()
+	// Far more lines than a binner with 4 buckets would keep, along with one stray line from long ago
+	for ts := int64(600); ts < 4400; ts++ {
+		if ts%2 == 0 {
+			b.addWithValue(ts, float64(ts))
+		} else {
+			other.add(ts)
+		}
+	}
+	other.add(-1_000_000_000_000)
+	b.merge(other)
+
+	if b.count != 4400-600+1 || b.valueCount != (4400-600)/2 {
+		t.Errorf("count = %d and valueCount = %d, want %d and %d", b.count, b.valueCount, 4400-600+1, (4400-600)/2)
+	}
+	if len(b.fixedBuckets) != 6 {
+		t.Errorf("got %d fixed buckets, want 6", len(b.fixedBuckets))
+	}
+
+	t.Run("counts each line in its bucket", func(t *testing.T) {
+		if got, want := b.fixedBins(0, 4), []float64{400, 1000, 1000, 1000, 400}; !reflect.DeepEqual(got, want) {
+			t.Errorf("fixedBins() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("aggregates the values in each bucket", func(t *testing.T) {
+		if got, want := b.fixedLevels(-1, 4), []float64{0, 998, 1998, 2998, 3998, 4398}; !reflect.DeepEqual(got, want) {
+			t.Errorf("fixedLevels() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("empty binners use the same buckets", func(t *testing.T) {
+		e := b.empty()
+		if e.grid == nil || *e.grid != grid || e.aggregation == nil {
+			t.Errorf("empty() = %+v, want the same fixed buckets and aggregation", e)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+
+	defaultGradient = "blue,green,yellow,red"
+	colorReset      = "\x1b[0m"
+)
+
+// colorModeFlag is the value of the -color flag. It can be given without a value to always use color, so like a bool
+// flag, any other value must follow an equals sign (-color=never).
+type colorModeFlag string
+
+func (f *colorModeFlag) String() string {
+	return string(*f)
+}
+
+func (f *colorModeFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*f = colorAlways
+	case "false":
+		*f = colorNever
+	case colorAuto, colorAlways, colorNever:
+		*f = colorModeFlag(value)
+	default:
+		return fmt.Errorf("must be '%s', '%s', or '%s'", colorAuto, colorAlways, colorNever)
+	}
+	return nil
+}
+
+func (f *colorModeFlag) IsBoolFlag() bool {
+	return true
+}
+
+// enabled reports whether to use color. In auto mode, color is used when writing to a terminal unless the NO_COLOR
+// environment variable is set to a non-empty value (see https://no-color.org).
+func (f colorModeFlag) enabled(isTerminal bool, noColor string) bool {
+	switch f {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	default:
+		return isTerminal && noColor == ""
+	}
+}
+
+type rgb struct {
+	r, g, b uint8
+}
+
+// namedColors are the colors that can be given by name in a gradient
+var namedColors = map[string]rgb{
+	"black":   {0x00, 0x00, 0x00},
+	"blue":    {0x45, 0x75, 0xb4},
+	"cyan":    {0x4d, 0xd0, 0xe1},
+	"gray":    {0x88, 0x88, 0x88},
+	"green":   {0x1a, 0x98, 0x50},
+	"magenta": {0xc5, 0x1b, 0x7d},
+	"orange":  {0xfc, 0x8d, 0x59},
+	"red":     {0xd7, 0x30, 0x1f},
+	"white":   {0xff, 0xff, 0xff},
+	"yellow":  {0xfe, 0xe0, 0x8b},
+}
+
+// seriesColors are the colors of the series when each one gets its own color. They're reused if there are more series.
+var seriesColors = []rgb{
+	namedColors["blue"],
+	namedColors["orange"],
+	namedColors["green"],
+	namedColors["magenta"],
+	namedColors["cyan"],
+	namedColors["yellow"],
+	namedColors["red"],
+}
+
+func parseColor(s string) (rgb, error) {
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 || hex == s {
+		return rgb{}, fmt.Errorf("unknown color '%s'", s)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return rgb{}, fmt.Errorf("unknown color '%s'", s)
+	}
+	return rgb{uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+}
+
+// foreground returns the escape sequence that draws text in the color.
+func (c rgb) foreground() string {
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.r, c.g, c.b)
+}
+
+// gradient is a list of colors that values from 0 to 1 are spread across.
+type gradient []rgb
+
+// parseGradient parses a comma-separated list of color names and hex codes like '#ff8800'.
+func parseGradient(s string) (gradient, error) {
+	var g gradient
+	for _, name := range strings.Split(s, ",") {
+		c, err := parseColor(strings.ToLower(strings.TrimSpace(name)))
+		if err != nil {
+			return nil, err
+		}
+		g = append(g, c)
+	}
+	return g, nil
+}
+
+// at blends the two colors that value falls between.
+func (g gradient) at(value float64) rgb {
+	if len(g) == 1 {
+		return g[0]
+	}
+	position := math.Max(0, math.Min(1, value)) * float64(len(g)-1)
+	i := int(position)
+	if i == len(g)-1 {
+		return g[i]
+	}
+	t := position - float64(i)
+	blend := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+	}
+	return rgb{blend(g[i].r, g[i+1].r), blend(g[i].g, g[i+1].g), blend(g[i].b, g[i+1].b)}
+}
+
+// colorScheme is how sparklines are colored. A nil *colorScheme draws them without color.
+type colorScheme struct {
+	gradient gradient
+	// bySeries colors each series in its own color instead of coloring each cell by its value
+	bySeries bool
+}
+
+// paint draws the cells. The series is the index of the sparkline among the series, or -1 if it isn't one of them.
+func (c *colorScheme) paint(cells []Cell, series int) string {
+	if c == nil {
+		return Text(cells)
+	}
+	if c.bySeries && series >= 0 {
+		return c.label(Text(cells), series)
+	}
+
+	var b strings.Builder
+	var current rgb
+	painted := false
+	for _, cell := range cells {
+		// Blank cells don't need a color of their own
+		if color := c.gradient.at(cell.Value); cell.Rune != ' ' && (!painted || color != current) {
+			b.WriteString(color.foreground())
+			current, painted = color, true
+		}
+		b.WriteRune(cell.Rune)
+	}
+	b.WriteString(colorReset)
+	return b.String()
+}
+
+// label draws the text in the series' color, if each series gets its own color.
+func (c *colorScheme) label(text string, series int) string {
+	if c == nil || !c.bySeries || series < 0 || strings.TrimSpace(text) == "" {
+		return text
+	}
+	return seriesColors[series%len(seriesColors)].foreground() + text + colorReset
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_colorModeFlag_enabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       colorModeFlag
+		isTerminal bool
+		noColor    string
+		want       bool
+	}{
+		{"auto on a terminal", colorAuto, true, "", true},
+		{"auto when not on a terminal", colorAuto, false, "", false},
+		{"auto with NO_COLOR", colorAuto, true, "1", false},
+		{"always with NO_COLOR", colorAlways, false, "1", true},
+		{"never", colorNever, true, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mode.enabled(tt.isTerminal, tt.noColor); got != tt.want {
+				t.Errorf("enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_colorModeFlag_Set(t *testing.T) {
+	for value, want := range map[string]colorModeFlag{"true": colorAlways, "false": colorNever, "auto": colorAuto, "never": colorNever} {
+		var f colorModeFlag
+		if err := f.Set(value); err != nil || f != want {
+			t.Errorf("Set(%q) = %q, %v, want %q", value, f, err, want)
+		}
+	}
+	var f colorModeFlag
+	if err := f.Set("sometimes"); err == nil {
+		t.Error("Set() expected an error but didn't get one")
+	}
+}
+
+func Test_parseGradient(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    gradient
+		wantErr bool
+	}{
+		{"red", gradient{namedColors["red"]}, false},
+		{"Black, #FF8800", gradient{{0, 0, 0}, {0xff, 0x88, 0x00}}, false},
+		{"pink", nil, true},
+		{"ff8800", nil, true},
+		{"#ff88", nil, true},
+		{"#gg8800", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseGradient(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGradient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGradient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_gradient_at(t *testing.T) {
+	g := gradient{{0, 0, 0}, {200, 100, 0}, {200, 200, 200}}
+	tests := []struct {
+		value float64
+		want  rgb
+	}{
+		{0, rgb{0, 0, 0}},
+		{0.25, rgb{100, 50, 0}},
+		{0.5, rgb{200, 100, 0}},
+		{0.75, rgb{200, 150, 100}},
+		{1, rgb{200, 200, 200}},
+		{2, rgb{200, 200, 200}},
+	}
+	for _, tt := range tests {
+		if got := g.at(tt.value); got != tt.want {
+			t.Errorf("at(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func Test_colorScheme_paint(t *testing.T) {
+	black, white := rgb{0, 0, 0}, rgb{255, 255, 255}
+	cells := []Cell{{' ', 0}, {'▁', 0}, {'▁', 0}, {'█', 1}}
+	tests := []struct {
+		name   string
+		colors *colorScheme
+		series int
+		want   string
+	}{
+		{"without color", nil, 0, " ▁▁█"},
+		{"by value", &colorScheme{gradient: gradient{black, white}}, -1, " \x1b[38;2;0;0;0m▁▁\x1b[38;2;255;255;255m█\x1b[0m"},
+		{"by series", &colorScheme{gradient: gradient{black, white}, bySeries: true}, 1, "\x1b[38;2;252;141;89m ▁▁█\x1b[0m"},
+		{"by series when it isn't a series", &colorScheme{gradient: gradient{white}, bySeries: true}, -1, " \x1b[38;2;255;255;255m▁▁█\x1b[0m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.colors.paint(cells, tt.series); got != tt.want {
+				t.Errorf("paint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"io"
+)
+
+type compressionFormat struct {
+	name          string
+	magic         []byte
+	newReaderFunc func(r io.Reader) (io.Reader, error)
+}
+
+var compressionFormats = []compressionFormat{
+	{
+		name:  "gzip",
+		magic: []byte{0x1f, 0x8b},
+		newReaderFunc: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		name:  "bzip2",
+		magic: []byte("BZh"),
+		newReaderFunc: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	},
+	{
+		name:  "zstd",
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		newReaderFunc: func(r io.Reader) (io.Reader, error) {
+			decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+			if err != nil {
+				return nil, err
+			}
+			return &zstdReader{decoder}, nil
+		},
+	},
+	{
+		name:  "xz",
+		magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		newReaderFunc: func(r io.Reader) (io.Reader, error) {
+			return xz.NewReader(r)
+		},
+	},
+}
+
+// maxMagicLength is the number of leading bytes needed to recognize any of the compression formats.
+const maxMagicLength = 6
+
+// detectCompressionFormat returns the compression format whose magic bytes the header starts with, or nil if the
+// header doesn't look compressed.
+func detectCompressionFormat(header []byte) *compressionFormat {
+	for i := range compressionFormats {
+		if bytes.HasPrefix(header, compressionFormats[i].magic) {
+			return &compressionFormats[i]
+		}
+	}
+	return nil
+}
+
+// decompressIfNeeded detects whether the input is compressed by looking at its first few bytes. If it is, then the
+// returned reader decompresses it. Otherwise, the returned reader yields the input unchanged.
+func decompressIfNeeded(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(maxMagicLength)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	format := detectCompressionFormat(header)
+	if format == nil {
+		return br, nil
+	}
+
+	decompressed, err := format.newReaderFunc(br)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s data: %v", format.name, err)
+	}
+	return decompressed, nil
+}
+
+// zstdReader releases the decoder's resources once the compressed stream has been fully read.
+type zstdReader struct {
+	decoder *zstd.Decoder
+}
+
+func (z *zstdReader) Read(buf []byte) (int, error) {
+	n, err := z.decoder.Read(buf)
+	if err != nil {
+		z.decoder.Close()
+	}
+	return n, err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"io"
+	"testing"
+)
+
+const bzip2HelloMom = "BZh91AY&SY\x5f\xc6\x9e\xef\x00\x00\x01\x51\x00\x00\x10\x40\x00\x00\x62\xa0\x00\x22\x0d\x3c\x83\x00\x08\x2b\x45\xdc\x91\x4e\x14\x24\x17\xf1\xa7\xbb\xc0"
+
+func compress(t *testing.T, newWriter func(io.Writer) (io.WriteCloser, error), s string) []byte {
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func Test_decompressIfNeeded(t *testing.T) {
+	const contents = "hi mom\n"
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"uncompressed", []byte(contents)},
+		{"empty", []byte{}},
+		{"gzip", compress(t, func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }, contents)},
+		{"bzip2", []byte(bzip2HelloMom)},
+		{"zstd", compress(t, func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }, contents)},
+		{"xz", compress(t, func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) }, contents)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := decompressIfNeeded(bytes.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("decompressIfNeeded() error = %v", err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("failed to read: %v", err)
+			}
+			want := contents
+			if len(tt.input) == 0 {
+				want = ""
+			}
+			if string(got) != want {
+				t.Errorf("decompressIfNeeded() read %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("for a corrupt header, returns an error", func(t *testing.T) {
+		if _, err := decompressIfNeeded(bytes.NewReader([]byte{0x1f, 0x8b, 0x00})); err == nil {
+			t.Error("decompressIfNeeded: expected an error but didn't get one")
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxFixedBuckets is the most fixed buckets that are drawn. Beyond that, the output would be too long to read.
+const maxFixedBuckets = 100000
+
+const day = 24 * time.Hour
+
+// bucketGrid divides time into buckets of a fixed duration. Buckets whose duration is a whole number of days, or
+// evenly divides a day, follow the calendar in location: they start at midnight and at multiples of the duration on
+// the wall clock after it, so a bucket can be longer or shorter on a day when the clocks change. Other buckets start at
+// multiples of the duration from midnight in location on the day of the Unix epoch.
+type bucketGrid struct {
+	duration time.Duration
+	location *time.Location
+}
+
+// bucket returns the index of the bucket that the time falls in. Consecutive buckets have consecutive indices.
+func (g bucketGrid) bucket(unixNanos int64) int64 {
+	t := time.Unix(0, unixNanos).In(g.location)
+	switch {
+	case g.duration%day == 0:
+		return floorDiv(daysSinceEpoch(t), int64(g.duration/day))
+	case day%g.duration == 0:
+		clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+			time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+		return daysSinceEpoch(t)*int64(day/g.duration) + int64(clock/g.duration)
+	default:
+		return floorDiv(unixNanos-g.wallClock(0, 0), int64(g.duration))
+	}
+}
+
+// start returns the time that the bucket starts at.
+func (g bucketGrid) start(bucket int64) int64 {
+	switch {
+	case g.duration%day == 0:
+		return g.wallClock(bucket*int64(g.duration/day), 0)
+	case day%g.duration == 0:
+		perDay := int64(day / g.duration)
+		days := floorDiv(bucket, perDay)
+		return g.wallClock(days, time.Duration(bucket-days*perDay)*g.duration)
+	default:
+		return g.wallClock(0, 0) + bucket*int64(g.duration)
+	}
+}
+
+// wallClock returns the time when the clock in the grid's location shows clock on the day that's the given number of
+// days after the Unix epoch.
+func (g bucketGrid) wallClock(days int64, clock time.Duration) int64 {
+	year, month, dayOfMonth := time.Unix(days*int64(day/time.Second), 0).UTC().Date()
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, int(clock), g.location).UnixNano()
+}
+
+// daysSinceEpoch returns the number of days between the Unix epoch and the date of t in its location.
+func daysSinceEpoch(t time.Time) int64 {
+	year, month, dayOfMonth := t.Date()
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, time.UTC).Unix() / int64(day/time.Second)
+}
+
+// rowSpans are the spans of time that a row of fixed buckets is made to cover when they can be, so that the rows start
+// at round times.
+var rowSpans = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// bucketsPerRow returns how many fixed buckets to draw in each row, which must be a multiple of step. It prefers the
+// longest of rowSpans that fits into maxBuckets, unless that would leave most of the row empty, and otherwise fills the
+// row.
+func bucketsPerRow(bucketDuration time.Duration, maxBuckets, step int) int {
+	perRow := max(maxBuckets-maxBuckets%step, step)
+	for _, span := range rowSpans {
+		if span%bucketDuration != 0 {
+			continue
+		}
+		if n := int(span / bucketDuration); n <= maxBuckets && n%step == 0 && n*4 >= maxBuckets {
+			perRow = n
+		}
+	}
+	return perRow
+}
+
+// fixedBucketTimeFormat returns the format of the labels of rows of fixed buckets. It leaves out the parts of the time
+// that are the same for every bucket.
+func fixedBucketTimeFormat(bucketDuration time.Duration) string {
+	switch {
+	case bucketDuration%(24*time.Hour) == 0:
+		return "Mon Jan 2"
+	case bucketDuration%time.Minute == 0:
+		return "Mon Jan 2 15:04"
+	default:
+		return markerTimeFormat(bucketDuration, 1)
+	}
+}
+
+// renderFixedBuckets draws the sparklines of the series, whose binners count lines into the same fixed buckets, for
+// the buckets that firstTime and lastTime fall in. The buckets are wrapped onto as many rows as it takes to fit them
+// into terminalWidth, and each row starts with the time of its first bucket. When there's more than one series, each
+// row has a sparkline for each of them, with labels that take up labelWidth columns. If labelWidth is 0, the series
+// aren't labeled. displayTime converts the times of the rows to the time zone that they're shown in.
+func renderFixedBuckets(rows []series, labelWidth int, firstTime, lastTime int64, displayTime func(unixNanos int64) time.Time, terminalWidth int, look sparklineLook) string {
+	grid := *rows[0].binner.grid
+	first, last := grid.bucket(firstTime), grid.bucket(lastTime)
+
+	layout := fixedBucketTimeFormat(grid.duration)
+	timeWidth := utf8.RuneCountInString(time.Date(2006, time.December, 22, 22, 22, 22, 999999999, time.UTC).Format(layout)) + 1
+	bucketsPerColumn := look.style.bucketsPerColumn()
+	perRow := bucketsPerRow(grid.duration, max(terminalWidth-timeWidth-labelWidth, 1)*bucketsPerColumn, bucketsPerColumn)
+	columnsPerRow := perRow / bucketsPerColumn
+
+	// The rows start at multiples of perRow buckets, so that the first one may start before the first bucket
+	start := floorDiv(first, int64(perRow)) * int64(perRow)
+	rowCount := int(floorDiv(last-start, int64(perRow)) + 1)
+	firstBucket := int(first - start)
+
+	// Only the buckets from the first one to the last one are scaled, so that the empty buckets that pad out the first
+	// and last rows don't count as lows. A column that's partly before the first bucket gets the lowest level there,
+	// which is then left out of the drawing.
+	leading := firstBucket % bucketsPerColumn
+	lines := make([][][]Cell, len(rows))
+	for i, row := range rows {
+		levels := row.binner.fixedLevels(first, last)
+		low := minimum(levels)
+		for j := 0; j < leading; j++ {
+			levels = append([]float64{low}, levels...)
+		}
+		for _, cells := range look.style.lines(levels, look.height) {
+			if leading > 0 {
+				cells[0] = withoutBrailleColumn(cells[0], 0)
+			}
+			padded := blankCells(firstBucket / bucketsPerColumn)
+			padded = append(padded, cells...)
+			padded = append(padded, blankCells(rowCount*columnsPerRow-len(padded))...)
+			lines[i] = append(lines[i], padded)
+		}
+	}
+
+	var b strings.Builder
+	for r := 0; r < rowCount; r++ {
+		rowTime := displayTime(grid.start(start + int64(r*perRow))).Format(layout)
+		for i, row := range rows {
+			seriesIndex := i
+			if labelWidth == 0 {
+				seriesIndex = -1
+			}
+			for line, cells := range lines[i] {
+				timeLabel, seriesText := rowTime, row.label
+				if i > 0 || line > 0 {
+					timeLabel = ""
+				}
+				if line > 0 {
+					seriesText = ""
+				}
+				fmt.Fprintf(&b, "%-*s", timeWidth, timeLabel)
+				if labelWidth > 0 {
+					b.WriteString(look.colors.label(fmt.Sprintf("%-*s", labelWidth, seriesLabel(seriesText)), seriesIndex))
+				}
+				b.WriteString(look.colors.paint(cells[r*columnsPerRow:(r+1)*columnsPerRow], seriesIndex))
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// blankCells returns n empty cells.
+func blankCells(n int) []Cell {
+	cells := make([]Cell, n)
+	for i := range cells {
+		cells[i].Rune = ' '
+	}
+	return cells
+}
+
+// withoutBrailleColumn removes the dots in one of the two columns of a braille cell.
+func withoutBrailleColumn(cell Cell, column int) Cell {
+	if cell.Rune < 0x2800 || cell.Rune > 0x28ff {
+		return cell
+	}
+	pattern := cell.Rune - 0x2800
+	for _, dot := range brailleDots[column] {
+		pattern &^= dot
+	}
+	if pattern == 0 {
+		cell.Rune = ' '
+	} else {
+		cell.Rune = 0x2800 + pattern
+	}
+	return cell
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_bucketsPerRow(t *testing.T) {
+	tests := []struct {
+		name           string
+		bucketDuration time.Duration
+		maxBuckets     int
+		step           int
+		want           int
+	}{
+		{"an hour of minutes", time.Minute, 63, 1, 60},
+		{"half an hour of minutes when an hour doesn't fit", time.Minute, 59, 1, 30},
+		{"a day of hours", time.Hour, 63, 1, 24},
+		{"days fill the row", 24 * time.Hour, 63, 1, 63},
+		{"uneven durations fill the row", 7 * time.Second, 63, 1, 63},
+		{"a multiple of the step", 24 * time.Hour, 63, 2, 62},
+		{"round spans must be a multiple of the step too", 20 * time.Minute, 10, 2, 6},
+		{"at least one step", time.Minute, 1, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bucketsPerRow(tt.bucketDuration, tt.maxBuckets, tt.step); got != tt.want {
+				t.Errorf("bucketsPerRow() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fixedBucketTimeFormat(t *testing.T) {
+	tests := []struct {
+		bucketDuration time.Duration
+		want           string
+	}{
+		{24 * time.Hour, "Mon Jan 2"},
+		{time.Hour, "Mon Jan 2 15:04"},
+		{time.Minute, "Mon Jan 2 15:04"},
+		{10 * time.Second, goAnsicTimeFormat},
+		{100 * time.Millisecond, goAnsicTimeFormat + ".0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucketDuration.String(), func(t *testing.T) {
+			if got := fixedBucketTimeFormat(tt.bucketDuration); got != tt.want {
+				t.Errorf("fixedBucketTimeFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderFixedBuckets(t *testing.T) {
+	start := time.Date(2019, time.November, 23, 6, 0, 0, 0, time.UTC)
+	minute := int64(time.Minute)
+	newBinner := func(minutes ...int64) *streamingBinner {
+		b := newStreamingBinner(80).withFixedBuckets(bucketGrid{duration: time.Minute, location: time.UTC})
+		for _, m := range minutes {
+			b.add(start.UnixNano() + m*minute)
+		}
+		return b
+	}
+	displayTime := func(unixNanos int64) time.Time {
+		return time.Unix(0, unixNanos).UTC()
+	}
+
+	t.Run("wraps the buckets into rows that start at round times", func(t *testing.T) {
+		b := newBinner(58, 59, 59, 61, 75)
+		got := renderFixedBuckets([]series{{binner: b}}, 0, b.firstTime, b.lastTime, displayTime, 30, sparklineLook{})
+
+		// Thirteen columns are left after the labels, so each row has ten minutes
+		want := "Sat Nov 23 06:50         ▅█\n" +
+			"Sat Nov 23 07:00 ▁▅▁▁▁▁▁▁▁▁\n" +
+			"Sat Nov 23 07:10 ▁▁▁▁▁▅    \n"
+		if got != want {
+			t.Errorf("renderFixedBuckets() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("scales only the buckets from the first line to the last", func(t *testing.T) {
+		b := newBinner(3, 3, 3, 3, 4, 4, 5, 5, 5, 6, 6, 6, 6, 7, 7)
+		got := renderFixedBuckets([]series{{binner: b}}, 0, b.firstTime, b.lastTime, displayTime, 30, sparklineLook{})
+
+		want := "Sat Nov 23 06:00    █▁▅█▁  \n"
+		if got != want {
+			t.Errorf("renderFixedBuckets() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("leaves out the half of a braille cell before the first bucket", func(t *testing.T) {
+		b := newBinner(3, 3, 3, 3, 4, 4, 5, 5, 5, 6, 6, 6, 6, 7, 7)
+		got := renderFixedBuckets([]series{{binner: b}}, 0, b.firstTime, b.lastTime, displayTime, 30, sparklineLook{style: styleBraille})
+
+		want := "Sat Nov 23 06:00  ⢸⣰⣇ \n"
+		if got != want {
+			t.Errorf("renderFixedBuckets() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("draws each series in each row", func(t *testing.T) {
+		get, post := newBinner(0, 1, 1), newBinner(3)
+		rows := []series{{"GET", get}, {"POST", post}}
+		got := renderFixedBuckets(rows, seriesLabelWidth(rows), get.firstTime, post.lastTime, displayTime, 27, sparklineLook{})
+
+		// Five minutes fit in each row
+		want := "Sat Nov 23 06:00 GET  ▅█▁▁ \n" +
+			"                 POST ▁▁▁█ \n"
+		if got != want {
+			t.Errorf("renderFixedBuckets() = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// followReader reads a file like `tail -f`: when it reaches the end of the file, it waits for more data to be
+// appended instead of returning io.EOF. If the file is truncated, it starts reading again from the beginning. If the
+// file is replaced (e.g. by log rotation), it finishes reading the old file and then switches to the new one.
+type followReader struct {
+	ctx          context.Context
+	filename     string
+	file         *os.File
+	offset       int64
+	pollInterval time.Duration
+}
+
+func newFollowReader(ctx context.Context, file *os.File, pollInterval time.Duration) *followReader {
+	return &followReader{
+		ctx:          ctx,
+		filename:     file.Name(),
+		file:         file,
+		offset:       0,
+		pollInterval: pollInterval,
+	}
+}
+
+// Read returns io.EOF only when the context is done.
+func (f *followReader) Read(buf []byte) (int, error) {
+	for {
+		n, err := f.file.Read(buf)
+		f.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		changed, err := f.reopenIfChanged()
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			continue
+		}
+
+		select {
+		case <-f.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(f.pollInterval):
+		}
+	}
+}
+
+// reopenIfChanged checks whether the file has been truncated or replaced since it was opened. It returns true if
+// reading should start over from the beginning of the (possibly new) file.
+func (f *followReader) reopenIfChanged() (bool, error) {
+	currentStat, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if currentStat.Size() < f.offset {
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		f.offset = 0
+		return true, nil
+	}
+
+	pathStat, err := os.Stat(f.filename)
+	if err != nil || os.SameFile(currentStat, pathStat) {
+		// Either the file hasn't been replaced, or it was moved away and its replacement hasn't been created yet
+		return false, nil
+	}
+
+	newFile, err := os.Open(f.filename)
+	if err != nil {
+		return false, nil
+	}
+	f.file.Close()
+	f.file = newFile
+	f.offset = 0
+	return true, nil
+}
+
+func (f *followReader) Close() error {
+	return f.file.Close()
+}
+
+// followSparkline keeps reading lines from r and redraws the sparkline in place every opts.followInterval. The sparkline
+// covers a sliding window of time that ends with the newest timestamp in the log. It returns when r is exhausted or the
+// context is done.
+func followSparkline(ctx context.Context, r io.Reader, w io.Writer, opts options) error {
+	timeFinder, err := newTimeFinder(&opts)
+	if err != nil {
+		return err
+	}
+
+	terminalWidth := opts.outputWidth()
+	var mu sync.Mutex
+	binner := newSlidingBinner(opts.followWindow, terminalWidth*opts.style.bucketsPerColumn())
+	timestampCount := 0
+
+	done := make(chan struct{})
+	var scanErr error
+	go func() {
+		_, scanErr = timeFinder.ForEachTimestamp(r, func(timestamp int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			binner.add(timestamp)
+			timestampCount++
+		})
+		close(done)
+	}()
+
+	linesDrawn := 0
+	redraw := func() {
+		mu.Lock()
+		if timestampCount == 0 {
+			mu.Unlock()
+			return
+		}
+		logLineCountPerCharacter := binner.bins()
+		firstTime, lastTime := binner.window()
+		mu.Unlock()
+
+		sparkline := renderSparkline(logLineCountPerCharacter, opts.displayTime(firstTime), opts.displayTime(lastTime), opts.timeMarkerCount, terminalWidth, opts.look())
+		if linesDrawn > 0 {
+			// Move back to the start of the previous drawing and clear it
+			fmt.Fprintf(w, "\x1b[%dF\x1b[J", linesDrawn)
+		}
+		fmt.Fprint(w, sparkline)
+		linesDrawn = strings.Count(sparkline, "\n")
+	}
+
+	ticker := time.NewTicker(opts.followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			redraw()
+		case <-done:
+			redraw()
+			if scanErr != nil {
+				return fmt.Errorf("failed to read log: %v", scanErr)
+			}
+			if timestampCount == 0 {
+				return fmt.Errorf("didn't find any lines with recognizable dates")
+			}
+			return nil
+		case <-ctx.Done():
+			redraw()
+			return nil
+		}
+	}
+}
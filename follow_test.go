@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_followReader(t *testing.T) {
+	readLine := func(t *testing.T, scanner *bufio.Scanner) string {
+		t.Helper()
+		if !scanner.Scan() {
+			t.Fatalf("failed to read line: %v", scanner.Err())
+		}
+		return scanner.Text()
+	}
+
+	setup := func(t *testing.T) (string, *bufio.Scanner) {
+		filename := filepath.Join(t.TempDir(), "follow.log")
+		if err := os.WriteFile(filename, []byte("one\n"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatalf("failed to open file: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		fr := newFollowReader(ctx, file, time.Millisecond)
+		t.Cleanup(func() {
+			cancel()
+			fr.Close()
+		})
+		return filename, bufio.NewScanner(fr)
+	}
+
+	appendLine := func(t *testing.T, filename, line string) {
+		t.Helper()
+		f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			t.Fatalf("failed to open file: %v", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(line + "\n"); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	t.Run("reads lines that are appended", func(t *testing.T) {
+		filename, scanner := setup(t)
+		if got := readLine(t, scanner); got != "one" {
+			t.Errorf("got %q, want %q", got, "one")
+		}
+		appendLine(t, filename, "two")
+		if got := readLine(t, scanner); got != "two" {
+			t.Errorf("got %q, want %q", got, "two")
+		}
+	})
+
+	t.Run("starts over when the file is truncated", func(t *testing.T) {
+		filename, scanner := setup(t)
+		readLine(t, scanner)
+		if err := os.WriteFile(filename, []byte(""), 0600); err != nil {
+			t.Fatalf("failed to truncate file: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		appendLine(t, filename, "2")
+		if got := readLine(t, scanner); got != "2" {
+			t.Errorf("got %q, want %q", got, "2")
+		}
+	})
+
+	t.Run("switches to the new file when the file is rotated", func(t *testing.T) {
+		filename, scanner := setup(t)
+		readLine(t, scanner)
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			t.Fatalf("failed to rotate file: %v", err)
+		}
+		appendLine(t, filename+".1", "two")
+		if err := os.WriteFile(filename, []byte("three\n"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if got := readLine(t, scanner); got != "two" {
+			t.Errorf("got %q, want %q", got, "two")
+		}
+		if got := readLine(t, scanner); got != "three" {
+			t.Errorf("got %q, want %q", got, "three")
+		}
+	})
+}
+
+func Test_followSparkline(t *testing.T) {
+	output := &bytes.Buffer{}
+	err := followSparkline(context.Background(), strings.NewReader(sampleLogLines), output, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 2, followWindow: 40 * time.Second, followInterval: time.Hour, width: 40})
+	if err != nil {
+		t.Fatalf("followSparkline() error = %v", err)
+	}
+
+	expected := "                     Sat Nov 23 06:26:48\n" +
+		"                                       |\n" +
+		strings.Repeat("▁", 30) + strings.Repeat("█", 10) + "\n" +
+		"|                                       \n" +
+		"Sat Nov 23 06:26:10                     \n"
+	if actual := output.String(); actual != expected {
+		t.Errorf("incorrect output\n\nwanted:\n%s\ngot:\n%s", expected, actual)
+	}
+}
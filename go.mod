@@ -4,7 +4,11 @@ go 1.24.0
 
 toolchain go1.24.10
 
-require golang.org/x/crypto v0.45.0
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/crypto v0.45.0
+)
 
 require (
 	golang.org/x/sys v0.38.0 // indirect
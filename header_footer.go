@@ -1,14 +1,34 @@
 package main
 
-import "time"
+import (
+	"math"
+	"strings"
+	"time"
+)
 
-func renderHeaderAndFooter(timestampsFromLines []int64, timeMarkerCount int, terminalWidth int) (string, string) {
+// markerTimeFormat returns the format for the time markers' labels. Fractional seconds are added when the markers are
+// less than a second apart, with enough digits that neighboring markers have different labels.
+func markerTimeFormat(duration time.Duration, timeMarkerCount int) string {
+	markerSpacing := duration
+	if timeMarkerCount > 1 {
+		markerSpacing /= time.Duration(timeMarkerCount - 1)
+	}
+	if markerSpacing >= time.Second || markerSpacing <= 0 {
+		return goAnsicTimeFormat
+	}
+
+	digits := int(math.Ceil(math.Log10(float64(time.Second) / float64(markerSpacing))))
+	if digits > 9 {
+		digits = 9
+	}
+	return goAnsicTimeFormat + "." + strings.Repeat("0", digits)
+}
+
+func renderHeaderAndFooter(firstTimestamp, lastTimestamp time.Time, timeMarkerCount int, terminalWidth int) (string, string) {
 	if timeMarkerCount == 0 {
 		return "", ""
 	}
 
-	firstTimestamp := time.Unix(timestampsFromLines[0], 0).UTC()
-	lastTimestamp := time.Unix(timestampsFromLines[len(timestampsFromLines)-1], 0).UTC()
 	duration := lastTimestamp.Sub(firstTimestamp)
 	footerMarkerCount := timeMarkerCount / 2
 	if timeMarkerCount%2 != 0 {
@@ -18,18 +38,19 @@ func renderHeaderAndFooter(timestampsFromLines []int64, timeMarkerCount int, ter
 
 	offsets := timeStemOffsets(timeMarkerCount, terminalWidth)
 	durationBetweenOffsets := time.Duration(duration.Nanoseconds() / int64(terminalWidth))
+	layout := markerTimeFormat(duration, timeMarkerCount)
 
 	headerOffsets := offsets[footerMarkerCount:]
-	headerCanvas := renderHeader(headerOffsets, terminalWidth, firstTimestamp, durationBetweenOffsets)
+	headerCanvas := renderHeader(headerOffsets, terminalWidth, firstTimestamp, durationBetweenOffsets, layout)
 
 	footerOffsets := offsets[0:footerMarkerCount]
-	footerCanvas := renderFooter(footerOffsets, terminalWidth, firstTimestamp, durationBetweenOffsets)
+	footerCanvas := renderFooter(footerOffsets, terminalWidth, firstTimestamp, durationBetweenOffsets, layout)
 	return headerCanvas.String(), footerCanvas.String()
 }
 
-func renderHeader(markerOffsets []int, terminalWidth int, firstTimestamp time.Time, durationBetweenOffsets time.Duration) canvas {
+func renderHeader(markerOffsets []int, terminalWidth int, firstTimestamp time.Time, durationBetweenOffsets time.Duration, layout string) canvas {
 	canvas := newCanvas(canvasTypeHeader, terminalWidth, len(markerOffsets)+1)
-	needStackedMarkers := (len(firstTimestamp.Format(goAnsicTimeFormat))+1)*len(markerOffsets) >= (terminalWidth / 2)
+	needStackedMarkers := (len(firstTimestamp.Format(layout))+1)*len(markerOffsets) >= (terminalWidth / 2)
 	for verticalOffset, horizontalOffset := range markerOffsets {
 		if needStackedMarkers {
 			verticalOffset += 2
@@ -40,14 +61,15 @@ func renderHeader(markerOffsets []int, terminalWidth int, firstTimestamp time.Ti
 		timeMarker{
 			horizontalOffset: horizontalOffset,
 			time:             firstTimestamp.Add(time.Duration(horizontalOffset) * durationBetweenOffsets),
+			layout:           layout,
 		}.render(canvas, verticalOffset, stemAlignmentRight)
 	}
 	return canvas
 }
 
-func renderFooter(markerOffsets []int, terminalWidth int, firstTimestamp time.Time, durationBetweenOffsets time.Duration) canvas {
+func renderFooter(markerOffsets []int, terminalWidth int, firstTimestamp time.Time, durationBetweenOffsets time.Duration, layout string) canvas {
 	canvas := newCanvas(canvasTypeFooter, terminalWidth, len(markerOffsets)+1)
-	needStackedMarkers := (len(firstTimestamp.Format(goAnsicTimeFormat))+1)*len(markerOffsets) >= (terminalWidth / 2)
+	needStackedMarkers := (len(firstTimestamp.Format(layout))+1)*len(markerOffsets) >= (terminalWidth / 2)
 	for verticalOffset, horizontalOffset := range markerOffsets {
 		if needStackedMarkers {
 			verticalOffset = len(markerOffsets) - verticalOffset + 1
@@ -58,6 +80,7 @@ func renderFooter(markerOffsets []int, terminalWidth int, firstTimestamp time.Ti
 		timeMarker{
 			horizontalOffset: horizontalOffset,
 			time:             firstTimestamp.Add(time.Duration(horizontalOffset) * durationBetweenOffsets),
+			layout:           layout,
 		}.render(canvas, verticalOffset, stemAlignmentLeft)
 	}
 	return canvas
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_markerTimeFormat(t *testing.T) {
+	tests := []struct {
+		name            string
+		duration        time.Duration
+		timeMarkerCount int
+		want            string
+	}{
+		{"long span", time.Hour, 10, goAnsicTimeFormat},
+		{"markers one second apart", 9 * time.Second, 10, goAnsicTimeFormat},
+		{"markers tenths of a second apart", 4500 * time.Millisecond, 10, goAnsicTimeFormat + ".0"},
+		{"markers milliseconds apart", 81 * time.Millisecond, 10, goAnsicTimeFormat + ".000"},
+		{"markers hundredths of a second apart", 90 * time.Millisecond, 10, goAnsicTimeFormat + ".00"},
+		{"markers nanoseconds apart", 9 * time.Nanosecond, 10, goAnsicTimeFormat + ".000000000"},
+		{"single marker", 500 * time.Millisecond, 1, goAnsicTimeFormat + ".0"},
+		{"empty span", 0, 10, goAnsicTimeFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markerTimeFormat(tt.duration, tt.timeMarkerCount); got != tt.want {
+				t.Errorf("markerTimeFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderHeaderAndFooterForShortSpan(t *testing.T) {
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	header, footer := renderHeaderAndFooter(first, first.Add(2*time.Second), 4, 80)
+
+	for _, label := range []string{"06:26:40.0", "06:26:40.6", "06:26:41.3", "06:26:41.9"} {
+		if !strings.Contains(header+footer, label) {
+			t.Errorf("renderHeaderAndFooter() is missing label %q\n%s\n%s", label, header, footer)
+		}
+	}
+}
+
+func Test_renderHeaderAndFooterAtNarrowWidths(t *testing.T) {
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	tests := []struct {
+		name            string
+		timeMarkerCount int
+		terminalWidth   int
+	}{
+		{"three markers", 3, 40},
+		{"two markers", 2, 20},
+		{"labels wider than the terminal", 2, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, footer := renderHeaderAndFooter(first, first.Add(time.Millisecond), tt.timeMarkerCount, tt.terminalWidth)
+			for _, line := range strings.Split(strings.TrimSuffix(header+footer, "\n"), "\n") {
+				if len(line) != tt.terminalWidth {
+					t.Errorf("renderHeaderAndFooter() line %q is %d wide, want %d", line, len(line), tt.terminalWidth)
+				}
+			}
+		})
+	}
+}
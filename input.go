@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/acj/krapslog/timefinder"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	stdinFilename = "-"
+	// autoDetectSampleLines is the number of lines at the start of the log that are used to detect its timestamp
+	// format.
+	autoDetectSampleLines = 1000
+)
+
+// openInputs opens the named log files for reading. The name "-" refers to standard input, which is also used if no
+// names are given. If any file can't be opened, the files that were already opened are closed.
+func openInputs(filenames []string) ([]*os.File, error) {
+	if len(filenames) == 0 {
+		filenames = []string{stdinFilename}
+	}
+
+	files := make([]*os.File, 0, len(filenames))
+	for _, filename := range filenames {
+		if filename == stdinFilename {
+			files = append(files, os.Stdin)
+			continue
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			closeInputs(files)
+			return nil, fmt.Errorf("error opening '%s': %v", filename, err)
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+func closeInputs(files []*os.File) {
+	for _, file := range files {
+		if file != os.Stdin {
+			file.Close()
+		}
+	}
+}
+
+// inputSize returns the size in bytes of the input. The second return value is false if the size can't be determined,
+// e.g. because the input is a pipe.
+func inputSize(r io.Reader) (int64, bool) {
+	if section, ok := r.(*io.SectionReader); ok {
+		return section.Size(), true
+	}
+
+	file, ok := r.(*os.File)
+	if !ok {
+		return 0, false
+	}
+
+	stat, err := file.Stat()
+	if err != nil || !stat.Mode().IsRegular() {
+		return 0, false
+	}
+	return stat.Size(), true
+}
+
+// latestModTime returns the most recent modification time of the inputs that are files. The second return value is
+// false if none of them are.
+func latestModTime(inputs []io.Reader) (time.Time, bool) {
+	var latest time.Time
+	for _, r := range inputs {
+		file, ok := r.(*os.File)
+		if !ok {
+			continue
+		}
+		stat, err := file.Stat()
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+		if stat.ModTime().After(latest) {
+			latest = stat.ModTime()
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// totalInputSize returns the combined size of the inputs, or zero if the size of any of them is unknown.
+func totalInputSize(inputs []io.Reader) int64 {
+	var total int64
+	for _, r := range inputs {
+		size, ok := inputSize(r)
+		if !ok {
+			return 0
+		}
+		total += size
+	}
+	return total
+}
+
+// narrowToTimeRange returns a reader for just the part of the input that contains the lines in the time finder's time
+// range. This avoids scanning the whole input when only a small part of a large log is needed. If the input can't be
+// searched, because it isn't a regular file, it's compressed, or its timestamps aren't in chronological order, then
+// the input is returned unchanged and will be scanned in full. It also returns the number of bytes that are skipped.
+func narrowToTimeRange(r io.Reader, timeFinder *timefinder.TimeFinder) (io.Reader, int64) {
+	readerAt, size, ok := seekableInput(r)
+	if !ok || size == 0 {
+		return r, 0
+	}
+
+	start, end, err := timeFinder.FindTimeRangeOffsets(readerAt, size)
+	if err != nil {
+		return r, 0
+	}
+	return io.NewSectionReader(readerAt, start, end-start), size - (end - start)
+}
+
+// seekableInput returns the input as an io.ReaderAt, along with its size, if it's a regular file (or a section of one)
+// that isn't compressed. Otherwise, it returns false.
+func seekableInput(r io.Reader) (io.ReaderAt, int64, bool) {
+	readerAt, ok := r.(io.ReaderAt)
+	if !ok {
+		return nil, 0, false
+	}
+	size, ok := inputSize(r)
+	if !ok {
+		return nil, 0, false
+	}
+
+	header := make([]byte, maxMagicLength)
+	n, err := readerAt.ReadAt(header, 0)
+	if (err != nil && err != io.EOF) || detectCompressionFormat(header[:n]) != nil {
+		return nil, 0, false
+	}
+	return readerAt, size, true
+}
+
+// detectDateFormat chooses a timestamp format based on the first lines of the input. Regular files are sampled without
+// disturbing them. Other inputs can't be rewound, so the returned reader replays the sampled data before continuing
+// with the rest of the input. If parseFields isn't nil, the lines are structured and only the values of the time field
+// are sampled.
+func detectDateFormat(r io.Reader, parseFields timefinder.FieldParser, timeField string) (timefinder.KnownFormat, io.Reader, error) {
+	var sampled bytes.Buffer
+	var sampleSource io.Reader
+	replay := r
+	if size, ok := inputSize(r); ok {
+		sampleSource = io.NewSectionReader(r.(io.ReaderAt), 0, size)
+	} else {
+		sampleSource = io.TeeReader(r, &sampled)
+		replay = io.MultiReader(&sampled, r)
+	}
+
+	decompressed, err := decompressIfNeeded(sampleSource)
+	if err != nil {
+		return timefinder.KnownFormat{}, nil, fmt.Errorf("failed to read log: %v", err)
+	}
+	br := bufio.NewReader(decompressed)
+	lines := make([]string, 0, autoDetectSampleLines)
+	sampledLines := 0
+	for sampledLines < autoDetectSampleLines {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			sampledLines++
+			if parseFields == nil {
+				lines = append(lines, line)
+			} else if fields, parseErr := parseFields(line); parseErr == nil {
+				if value, ok := fields.Field(timeField); ok {
+					lines = append(lines, value)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return timefinder.KnownFormat{}, nil, fmt.Errorf("failed to read log: %v", err)
+		}
+	}
+
+	if parseFields != nil && len(lines) == 0 && sampledLines > 0 {
+		return timefinder.KnownFormat{}, nil, fmt.Errorf("couldn't detect timestamp format: none of the first %d lines has a '%s' field", sampledLines, timeField)
+	}
+	format, err := timefinder.DetectTimeFormat(lines)
+	if err != nil {
+		return timefinder.KnownFormat{}, nil, fmt.Errorf("couldn't detect timestamp format: %v", err)
+	}
+	return format, replay, nil
+}
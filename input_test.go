@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/acj/krapslog/timefinder"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_totalInputSize(t *testing.T) {
+	dir := t.TempDir()
+	var files []*os.File
+	for i, contents := range []string{"hi mom\n", "hi dad\n"} {
+		filename := filepath.Join(dir, string(rune('a'+i))+".log")
+		if err := os.WriteFile(filename, []byte(contents), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatalf("failed to open file: %v", err)
+		}
+		defer file.Close()
+		files = append(files, file)
+	}
+
+	t.Run("for regular files, returns the combined size", func(t *testing.T) {
+		if got := totalInputSize([]io.Reader{files[0], files[1]}); got != 14 {
+			t.Errorf("totalInputSize() = %d, want %d", got, 14)
+		}
+	})
+
+	t.Run("when any size is unknown, returns zero", func(t *testing.T) {
+		if got := totalInputSize([]io.Reader{files[0], strings.NewReader("hi mom\n")}); got != 0 {
+			t.Errorf("totalInputSize() = %d, want %d", got, 0)
+		}
+	})
+}
+
+func Test_narrowToTimeRange(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(filename, []byte(sampleLogLines), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	timeFinder, err := newTimeFinder(&options{dateFormat: apacheCommonLogFormatDate, since: "2019-11-23T06:26:43Z", until: "2019-11-23T06:26:45Z"})
+	if err != nil {
+		t.Fatalf("newTimeFinder() error = %v", err)
+	}
+
+	t.Run("for a regular file, reads only the lines in the range", func(t *testing.T) {
+		narrowed, skipped := narrowToTimeRange(file, timeFinder)
+		got, err := io.ReadAll(narrowed)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		lines := strings.SplitAfter(sampleLogLines, "\n")
+		if want := lines[3] + lines[4]; string(got) != want {
+			t.Errorf("narrowToTimeRange() read %q, want %q", got, want)
+		}
+		if want := int64(len(sampleLogLines) - len(got)); skipped != want {
+			t.Errorf("narrowToTimeRange() skipped %d bytes, want %d", skipped, want)
+		}
+	})
+
+	t.Run("for a reader that isn't a file, returns the reader unchanged", func(t *testing.T) {
+		r := strings.NewReader(sampleLogLines)
+		if got, skipped := narrowToTimeRange(r, timeFinder); got != r || skipped != 0 {
+			t.Errorf("narrowToTimeRange() = %v, %d, want %v, 0", got, skipped, r)
+		}
+	})
+}
+
+func Test_detectDateFormat(t *testing.T) {
+	t.Run("for a stream, replays the sampled lines", func(t *testing.T) {
+		format, r, err := detectDateFormat(io.MultiReader(strings.NewReader(sampleLogLines)), nil, "")
+		if err != nil {
+			t.Fatalf("detectDateFormat() error = %v", err)
+		}
+		if format.Layout != apacheCommonLogFormatDate {
+			t.Errorf("detectDateFormat() format = %q, want %q", format.Layout, apacheCommonLogFormatDate)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if string(got) != sampleLogLines {
+			t.Errorf("detectDateFormat() reader yielded %q, want %q", got, sampleLogLines)
+		}
+	})
+
+	t.Run("for input without timestamps, returns an error", func(t *testing.T) {
+		if _, _, err := detectDateFormat(strings.NewReader("hi mom\n"), nil, ""); err == nil {
+			t.Error("detectDateFormat: expected an error but didn't get one")
+		}
+	})
+
+	t.Run("for JSON lines, samples only the time field", func(t *testing.T) {
+		// The messages hold more RFC3339 timestamps than the time fields hold epoch timestamps
+		lines := `{"ts": 1574490400, "msg": "deploy at 2019-11-23T06:00:00Z", "until": "2019-11-23T07:00:00Z"}
+{"ts": 1574490401, "msg": "retry at 2019-11-23T06:30:00Z"}
+`
+		format, _, err := detectDateFormat(strings.NewReader(lines), timefinder.ParseJSON, "ts")
+		if err != nil {
+			t.Fatalf("detectDateFormat() error = %v", err)
+		}
+		if format.Layout != "epoch" {
+			t.Errorf("detectDateFormat() format = %q, want %q", format.Layout, "epoch")
+		}
+	})
+}
@@ -1,84 +1,573 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/acj/krapslog/timefinder"
 	"golang.org/x/crypto/ssh/terminal"
 	"io"
+	"math"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
 	apacheCommonLogFormatDate = "02/Jan/2006:15:04:05.000"
 	goAnsicDateFormat         = "Mon Jan 2 15:04:05 2006"
+	// defaultWidth is the width of the output, in columns, when it isn't given and can't be detected
+	defaultWidth = 80
 )
 
+// options controls how logs are scanned and how the sparkline is displayed.
+type options struct {
+	dateFormat      string
+	timeMarkerCount int
+	displayProgress bool
+	timeRange       timefinder.TimeRange
+	// since and until are the -since and -until values. They're parsed into timeRange by newTimeFinder, once the date
+	// format, year, and reference time are known, so they're read like the log's own timestamps.
+	since, until   string
+	year           int
+	referenceTime  time.Time
+	followWindow   time.Duration
+	followInterval time.Duration
+	// inputLocation is where timestamps without a UTC offset were written. If it's nil, UTC is assumed.
+	inputLocation *time.Location
+	// displayLocation is the time zone of the time markers. If it's nil, UTC is used.
+	displayLocation *time.Location
+	// reorderTolerance is how far a line's timestamp can go back in time before the line is reported as out of order
+	reorderTolerance time.Duration
+	// fieldParser parses structured lines, like JSON objects or logfmt, into fields, and timeField is the path of the
+	// field that holds the timestamp. When fieldParser is set, the line filters, splitPattern, and valuePattern refer to
+	// fields instead of the whole line. If it's nil, the lines aren't structured.
+	fieldParser timefinder.FieldParser
+	timeField   string
+	// includePatterns and excludePatterns select the lines that are counted
+	includePatterns []string
+	excludePatterns []string
+	// splitPattern splits the lines into series that get their own sparklines, or is empty to count all lines together
+	splitPattern string
+	// topSeries is the number of series that are shown. The rest are combined into one labeled "other".
+	topSeries int
+	// valuePattern finds a value in each line, or is empty to count lines instead of aggregating their values
+	valuePattern string
+	// aggregation is how the values in each bucket are combined
+	aggregation aggregation
+	// statsFormat is the format of the scan report that's printed after the sparkline, or empty for no report
+	statsFormat string
+	// maxLineLength is the length, in bytes, of the longest line that's scanned. If it's zero, the default is used.
+	maxLineLength int
+	// parallelism is the number of goroutines that scan each large input. Values less than 2 disable parallel scanning.
+	parallelism int
+	// height is the number of rows in each sparkline. Values less than 2 draw the usual one-row sparkline.
+	height int
+	// style is how the sparklines are drawn
+	style sparklineStyle
+	// colors is how the sparklines are colored, or nil to draw them without color
+	colors *colorScheme
+	// width is the width of the output in columns. If it's zero, defaultWidth is used.
+	width int
+	// bucketDuration is the duration of each bucket, or 0 to divide the range of the timestamps into one bucket for each
+	// column. Buckets with a fixed duration are wrapped onto as many rows as it takes to show them.
+	bucketDuration time.Duration
+}
+
+// outputWidth returns the width of the output in columns.
+func (opts options) outputWidth() int {
+	if opts.width > 0 {
+		return opts.width
+	}
+	return defaultWidth
+}
+
+// bucketGrid returns how lines are counted into buckets with a fixed duration. The buckets follow the calendar in
+// the display location, so the ones whose duration evenly divides a day start on the minute, hour, or day there.
+func (opts options) bucketGrid() bucketGrid {
+	location := time.UTC
+	if opts.displayLocation != nil {
+		location = opts.displayLocation
+	}
+	return bucketGrid{duration: opts.bucketDuration, location: location}
+}
+
+// look returns how the sparklines are drawn.
+func (opts options) look() sparklineLook {
+	return sparklineLook{style: opts.style, height: opts.height, colors: opts.colors}
+}
+
+// displayTime converts nanoseconds since the Unix epoch to a time in the display location.
+func (opts options) displayTime(unixNanos int64) time.Time {
+	if opts.displayLocation == nil {
+		return time.Unix(0, unixNanos).UTC()
+	}
+	return time.Unix(0, unixNanos).In(opts.displayLocation)
+}
+
 func main() {
 	var displayProgress = flag.Bool("progress", false, "display progress while scanning the log file")
-	var requestedDateFormat = flag.String("format", apacheCommonLogFormatDate, "date format to look for (see https://golang.org/pkg/time/#Time.Format)")
+	var requestedDateFormat = flag.String("format", apacheCommonLogFormatDate, "date format to look for (see https://golang.org/pkg/time/#Time.Format), one of 'epoch', 'epoch_ms', 'epoch_us', or 'epoch_ns' for Unix timestamps, or 'auto' to detect it")
 	var timeMarkerCount = flag.Int("markers", 0, "number of time markers to display")
+	var width = flag.Int("width", 0, "width of the output in columns (default: the width of the terminal, or else $COLUMNS, or else 80)")
+	var bucketDuration = flag.Duration("bucket", 0, "count lines into buckets of this duration, like 1m or 1h, that start on the minute, hour, or day in the -tz time zone, instead of fitting the buckets to the width; rows of buckets that don't fit are wrapped, and each is labeled with its time instead of using -markers")
+	var height = flag.Int("height", 1, "number of rows in the sparkline; each row adds eight levels of resolution, so small changes stand out")
+	style := sparklineStyle(styleBlocks)
+	flag.Var(&style, "style", "how the sparkline is drawn: 'blocks', or 'braille' to fit two buckets into each column (with four levels per row instead of eight)")
+	colorMode := colorModeFlag(colorAuto)
+	flag.Var(&colorMode, "color", "shade the sparkline by the height of each column: 'auto' to do it when writing to a terminal and NO_COLOR isn't set, 'always' (the default if no value is given), or 'never'; a value must follow an equals sign, like -color=never")
+	var gradientColors = flag.String("gradient", defaultGradient, "with -color, the colors that the lowest to highest columns are shaded with, as a comma-separated list of names (black, blue, cyan, gray, green, magenta, orange, red, white, or yellow) or hex codes like '#ff8800'")
+	var colorSeries = flag.Bool("color-series", false, "with -color and -split-by, draw each series in its own color instead of shading by height")
+	var follow = flag.Bool("follow", false, "keep reading as lines are appended to the log and redraw the sparkline periodically")
+	var followWindow = flag.Duration("window", 10*time.Minute, "in follow mode, the span of time covered by the sparkline")
+	var followInterval = flag.Duration("interval", 2*time.Second, "in follow mode, how often to redraw the sparkline")
+	var since = flag.String("since", "", "ignore lines before this time (an RFC3339 timestamp, a timestamp in the -format layout, or a duration like 2h that's measured back from now)")
+	var until = flag.String("until", "", "ignore lines after this time (same formats as -since)")
+	var year = flag.Int("year", 0, "for date formats without a year, the year of the first line (default: inferred from the modification time of the log)")
+	var reorderTolerance = flag.Duration("reorder-tolerance", time.Second, "how far a timestamp can be earlier than the ones before it before its line is reported as out of order")
+	var maxLineLength = flag.Int("max-line-length", timefinder.DefaultMaxLineLength, "length, in bytes, of the longest line to scan for a timestamp; longer lines are skipped")
+	var jsonLines = flag.Bool("json", false, "read each line as a JSON object, with the timestamp in the -time-field field; -match, -exclude, -split-by, and -value then refer to fields (and -format defaults to 'auto')")
+	var logfmtLines = flag.Bool("logfmt", false, "read each line as logfmt key=value pairs, with the timestamp in the -time-field field; -match, -exclude, -split-by, and -value then refer to keys (and -format defaults to 'auto')")
+	var timeField = flag.String("time-field", "", "with -json or -logfmt, the field that holds the timestamp, which can be a dotted path like 'request.time' to reach into nested objects (default: 'time' for JSON, 'ts' for logfmt)")
+	var includePatterns, excludePatterns stringsFlag
+	flag.Var(&includePatterns, "match", "only count lines that match this regular expression (can be repeated to count lines that match any of them); with -json or -logfmt, only count lines where a field matches, given as FIELD=REGEX")
+	flag.Var(&excludePatterns, "exclude", "don't count lines that match this regular expression (can be repeated); with -json or -logfmt, given as FIELD=REGEX")
+	var splitPattern = flag.String("split-by", "", "draw a separate sparkline for each value of the first capture group of this regular expression (or of the whole match if it has no groups); with -json or -logfmt, for each value of this field")
+	var topSeries = flag.Int("top", 5, "with -split-by, the number of series to draw; the rest are combined into one labeled 'other'")
+	var valuePattern = flag.String("value", "", "instead of counting lines, aggregate the number in the first capture group of this regular expression (or in the whole match if it has no groups); with -json or -logfmt, the number in this field")
+	var valueAggregation aggregation
+	flag.Var(&valueAggregation, "agg", "with -value, how the values in each bucket are combined: 'sum', 'avg', 'min', 'max', or a percentile like 'p50' or 'p99'")
+	var statsFormat statsFormatFlag
+	flag.Var(&statsFormat, "stats", "after the sparkline, report how many lines were scanned, matched, and skipped, the first and last timestamps, and the peak and average rates, as 'text' (the default if no value is given) or 'json'; a value must follow an equals sign, like -stats=json")
+	var parallelism = flag.Int("parallel", runtime.NumCPU(), "number of goroutines used to scan each large, uncompressed log file")
+	var displayTimeZone = flag.String("tz", "UTC", "time zone of the time markers, as an IANA name like America/New_York or 'Local'")
+	var inputTimeZone = flag.String("input-tz", "UTC", "time zone of timestamps that don't include a UTC offset, as an IANA name like America/New_York or 'Local'")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [file ...]\n\nReads standard input if no files are given or if a file is named '-'.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	if flag.NArg() == 0 {
-		exitWithErrorMessage("no filename given")
+	var err error
+	opts := options{
+		dateFormat:       *requestedDateFormat,
+		timeMarkerCount:  *timeMarkerCount,
+		width:            *width,
+		bucketDuration:   *bucketDuration,
+		height:           *height,
+		style:            style,
+		displayProgress:  *displayProgress,
+		year:             *year,
+		followWindow:     *followWindow,
+		followInterval:   *followInterval,
+		reorderTolerance: *reorderTolerance,
+		parallelism:      *parallelism,
+		maxLineLength:    *maxLineLength,
+		statsFormat:      string(statsFormat),
+		includePatterns:  includePatterns,
+		excludePatterns:  excludePatterns,
+		splitPattern:     *splitPattern,
+		topSeries:        *topSeries,
+		valuePattern:     *valuePattern,
+		aggregation:      valueAggregation,
+	}
+	if opts.width < 0 {
+		exitWithErrorMessage("-width must be positive")
+	}
+	if opts.width == 0 {
+		opts.width = detectWidth([]int{int(os.Stdout.Fd()), int(os.Stderr.Fd())}, terminal.GetSize, os.Getenv("COLUMNS"))
+	}
+	if opts.bucketDuration < 0 {
+		exitWithErrorMessage("-bucket must be positive")
+	}
+	if opts.bucketDuration > 0 && opts.timeMarkerCount > 0 {
+		exitWithErrorMessage("-bucket and -markers can't be used together")
+	}
+	if opts.height < 1 {
+		exitWithErrorMessage("-height must be at least 1")
+	}
+	if colorMode.enabled(terminal.IsTerminal(int(os.Stdout.Fd())), os.Getenv("NO_COLOR")) {
+		g, err := parseGradient(*gradientColors)
+		if err != nil {
+			exitWithErrorMessage("invalid -gradient value: %v", err)
+		}
+		opts.colors = &colorScheme{gradient: g, bySeries: *colorSeries}
+	}
+	if *jsonLines && *logfmtLines {
+		exitWithErrorMessage("-json and -logfmt can't be used together")
+	}
+	if *jsonLines {
+		opts.fieldParser, opts.timeField = timefinder.ParseJSON, "time"
+	} else if *logfmtLines {
+		opts.fieldParser, opts.timeField = timefinder.ParseLogfmt, "ts"
+	}
+	if opts.fieldParser != nil {
+		if *timeField != "" {
+			opts.timeField = *timeField
+		}
+		if !isFlagSet("format") {
+			opts.dateFormat = timefinder.AutoFormat
+		}
+	} else if *timeField != "" {
+		exitWithErrorMessage("-time-field requires -json or -logfmt")
+	}
+	if opts.displayLocation, err = time.LoadLocation(*displayTimeZone); err != nil {
+		exitWithErrorMessage("invalid -tz value: %v", err)
+	}
+	if opts.inputLocation, err = time.LoadLocation(*inputTimeZone); err != nil {
+		exitWithErrorMessage("invalid -input-tz value: %v", err)
+	}
+	opts.since, opts.until = *since, *until
+
+	if flag.NArg() == 0 && terminal.IsTerminal(int(os.Stdin.Fd())) {
+		exitWithErrorMessage("no filename given and standard input is a terminal")
 	}
 
-	filename := flag.Arg(0)
-	file, err := os.Open(filename)
+	files, err := openInputs(flag.Args())
 	if err != nil {
-		exitWithErrorMessage("error opening '%s': %v", filename, err)
+		exitWithErrorMessage("%v", err)
 	}
-	defer file.Close()
+	defer closeInputs(files)
 
-	if err := displaySparkline(file, os.Stdout, *requestedDateFormat, *timeMarkerCount, *displayProgress); err != nil {
+	if *follow {
+		if len(files) != 1 {
+			closeInputs(files)
+			exitWithErrorMessage("follow mode supports exactly one input")
+		}
+		if opts.valuePattern != "" {
+			closeInputs(files)
+			exitWithErrorMessage("follow mode doesn't support -value")
+		}
+		if opts.bucketDuration > 0 {
+			closeInputs(files)
+			exitWithErrorMessage("follow mode doesn't support -bucket")
+		}
+		if opts.splitPattern != "" || isFlagSet("top") {
+			closeInputs(files)
+			exitWithErrorMessage("follow mode doesn't support -split-by or -top")
+		}
+		if opts.statsFormat != statsFormatNone {
+			closeInputs(files)
+			exitWithErrorMessage("follow mode doesn't support -stats")
+		}
+		if err := runFollowMode(files[0], opts); err != nil {
+			closeInputs(files)
+			exitWithErrorMessage("couldn't generate sparkline: %v", err)
+		}
+		return
+	}
+
+	inputs := make([]io.Reader, len(files))
+	for i, file := range files {
+		inputs[i] = file
+	}
+
+	if err := displaySparkline(inputs, os.Stdout, opts); err != nil {
+		closeInputs(files)
 		exitWithErrorMessage("couldn't generate sparkline: %v", err)
 	}
+}
 
-	os.Exit(0)
+// parseTimeRange parses the -since and -until values like the timestamps that timeFinder finds. Either may be empty to
+// leave that end of the range open.
+func parseTimeRange(since, until string, timeFinder *timefinder.TimeFinder, now time.Time) (timefinder.TimeRange, error) {
+	var timeRange timefinder.TimeRange
+	var err error
+	if since != "" {
+		if timeRange.Since, err = timeFinder.ParseRangeBound(since, now); err != nil {
+			return timefinder.TimeRange{}, fmt.Errorf("invalid -since value: %v", err)
+		}
+	}
+	if until != "" {
+		if timeRange.Until, err = timeFinder.ParseRangeBound(until, now); err != nil {
+			return timefinder.TimeRange{}, fmt.Errorf("invalid -until value: %v", err)
+		}
+	}
+	if !timeRange.Since.IsZero() && !timeRange.Until.IsZero() && timeRange.Until.Before(timeRange.Since) {
+		return timefinder.TimeRange{}, fmt.Errorf("-until (%v) is before -since (%v)", timeRange.Until, timeRange.Since)
+	}
+	return timeRange, nil
 }
 
-func displaySparkline(r io.Reader, w io.Writer, dateFormat string, timeMarkerCount int, shouldDisplayProgress bool) error {
-	timeFinder, err := timefinder.NewTimeFinder(dateFormat)
+// resolveDateFormat replaces the 'auto' date format with the format detected from the input. It returns a reader that
+// should be used in place of the input.
+func resolveDateFormat(opts *options, r io.Reader) (io.Reader, error) {
+	if opts.dateFormat != timefinder.AutoFormat {
+		return r, nil
+	}
+
+	format, r, err := detectDateFormat(r, opts.fieldParser, opts.timeField)
 	if err != nil {
-		return fmt.Errorf("invalid timestamp format: %v", err)
+		return nil, err
 	}
+	fmt.Fprintf(os.Stderr, "detected timestamp format '%s' (%s)\n", format.Layout, format.Name)
+	opts.dateFormat = format.Layout
+	return r, nil
+}
 
-	if shouldDisplayProgress {
-		r, err = NewProgressReader(r, func(progressPercent float64) {
-			fmt.Fprintf(os.Stderr, "\r%.f%%", progressPercent)
-			if progressPercent == 100.0 {
-				fmt.Fprintf(os.Stderr, "\r")
-			}
-		})
+// newTimeFinder creates the TimeFinder for the options. The date format must already be resolved. Once the TimeFinder
+// knows how to read the log's timestamps, the -since and -until values are parsed like them into opts.timeRange.
+func newTimeFinder(opts *options) (*timefinder.TimeFinder, error) {
+	timeFinder, err := timefinder.NewTimeFinder(opts.dateFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp format: %v", err)
+	}
+	if !opts.referenceTime.IsZero() {
+		timeFinder.SetReferenceTime(opts.referenceTime)
+	}
+	if opts.year != 0 {
+		timeFinder.SetYear(opts.year)
+	}
+	if opts.inputLocation != nil {
+		timeFinder.SetLocation(opts.inputLocation)
+	}
+	timeFinder.SetReorderTolerance(opts.reorderTolerance)
+	if opts.maxLineLength > 0 {
+		timeFinder.SetMaxLineLength(opts.maxLineLength)
+	}
+	if opts.fieldParser != nil {
+		timeFinder.SetTimeField(opts.fieldParser, opts.timeField)
+	}
+	if len(opts.includePatterns) > 0 || len(opts.excludePatterns) > 0 {
+		newFilter := timefinder.NewLineFilter
+		if opts.fieldParser != nil {
+			newFilter = timefinder.NewFieldFilter
+		}
+		filter, err := newFilter(opts.includePatterns, opts.excludePatterns)
 		if err != nil {
-			return fmt.Errorf("failed to read log: %v", err)
+			return nil, fmt.Errorf("invalid -match or -exclude pattern: %v", err)
 		}
+		timeFinder.SetLineFilter(filter)
 	}
+	if opts.since != "" || opts.until != "" {
+		if opts.timeRange, err = parseTimeRange(opts.since, opts.until, timeFinder, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	timeFinder.SetTimeRange(opts.timeRange)
+	return timeFinder, nil
+}
 
-	timestampsFromLines := timeFinder.ExtractTimestampFromEachLine(r)
-	if len(timestampsFromLines) == 0 {
-		return fmt.Errorf("didn't find any lines with recognizable dates")
+func runFollowMode(file *os.File, opts options) error {
+	if opts.followWindow <= 0 || opts.followInterval <= 0 {
+		return fmt.Errorf("window and interval must be positive")
 	}
 
-	terminalWidth, _, err := terminal.GetSize(int(os.Stdin.Fd()))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r, err := resolveDateFormat(&opts, file)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "couldn't get terminal size (%v); defaulting to 80 characters\n", err)
-		terminalWidth = 80
+		return err
+	}
+	if _, ok := inputSize(file); ok {
+		fr := newFollowReader(ctx, file, opts.followInterval)
+		defer fr.Close()
+		r = fr
+	}
+
+	return followSparkline(ctx, r, os.Stdout, opts)
+}
+
+func displaySparkline(inputs []io.Reader, w io.Writer, opts options) error {
+	if len(inputs) > 0 {
+		// Only the first input is sampled. The others are assumed to use the same format.
+		firstInput, err := resolveDateFormat(&opts, inputs[0])
+		if err != nil {
+			return err
+		}
+		inputs = append([]io.Reader{firstInput}, inputs[1:]...)
+	}
+	if modTime, ok := latestModTime(inputs); ok && opts.referenceTime.IsZero() {
+		opts.referenceTime = modTime
 	}
 
-	logLineCountPerCharacter := binTimestamps(timestampsFromLines, terminalWidth)
-	sparkLine := Line(logLineCountPerCharacter)
+	timeFinder, err := newTimeFinder(&opts)
+	if err != nil {
+		return err
+	}
 
-	header, footer := renderHeaderAndFooter(timestampsFromLines, timeMarkerCount, terminalWidth)
+	var skippedBytes int64
+	if opts.timeRange != (timefinder.TimeRange{}) {
+		narrowedInputs := make([]io.Reader, len(inputs))
+		for i, r := range inputs {
+			var skipped int64
+			narrowedInputs[i], skipped = narrowToTimeRange(r, timeFinder)
+			skippedBytes += skipped
+		}
+		inputs = narrowedInputs
+	}
 
-	fmt.Fprint(w, header)
-	fmt.Fprintln(w, sparkLine)
-	fmt.Fprint(w, footer)
+	var tracker *progressTracker
+	if opts.displayProgress {
+		tracker = newProgressTracker(totalInputSize(inputs), printProgress)
+	}
+
+	terminalWidth := opts.outputWidth()
+	bucketCount := terminalWidth * opts.style.bucketsPerColumn()
+	binner := newStreamingBinner(bucketCount)
+	s := scanner{
+		timeFinder:  timeFinder,
+		order:       newOrderChecker(int64(opts.reorderTolerance)),
+		tracker:     tracker,
+		parallelism: opts.parallelism,
+	}
+	if opts.valuePattern != "" {
+		if opts.fieldParser != nil {
+			s.values = newFieldValueExtractor(opts.valuePattern)
+		} else if s.values, err = newValueExtractor(opts.valuePattern); err != nil {
+			return fmt.Errorf("invalid -value pattern: %v", err)
+		}
+		binner = newValueBinner(bucketCount, opts.aggregation)
+	}
+	if opts.bucketDuration > 0 {
+		binner.withFixedBuckets(opts.bucketGrid())
+	}
+	s.binner = binner
+	if opts.splitPattern != "" {
+		if opts.fieldParser != nil {
+			s.splitter = newFieldSeriesSplitter(opts.splitPattern)
+		} else if s.splitter, err = newSeriesSplitter(opts.splitPattern); err != nil {
+			return fmt.Errorf("invalid -split-by pattern: %v", err)
+		}
+		s.series = newSeriesBinners(binner)
+	}
+	for _, r := range inputs {
+		if err := s.scan(r); err != nil {
+			return err
+		}
+	}
+	if opts.displayProgress {
+		fmt.Fprint(os.Stderr, "\r")
+	}
+	report := newScanReport(s.stats, s.order.outOfOrder)
+	report.SkippedBytes = skippedBytes
+	if binner.count == 0 {
+		if opts.statsFormat != statsFormatNone {
+			if err := report.write(w, opts.statsFormat); err != nil {
+				return err
+			}
+		}
+		if opts.timeRange != (timefinder.TimeRange{}) {
+			return fmt.Errorf("didn't find any lines with recognizable dates in the selected time range")
+		}
+		return fmt.Errorf("didn't find any lines with recognizable dates")
+	}
+	if s.values != nil && binner.valueCount == 0 {
+		return fmt.Errorf("didn't find any values matching the -value pattern")
+	}
+	if s.stats.LongLines > 0 {
+		maxLineLength := opts.maxLineLength
+		if maxLineLength <= 0 {
+			maxLineLength = timefinder.DefaultMaxLineLength
+		}
+		fmt.Fprintf(os.Stderr, "skipped %d lines longer than %d bytes (see -max-line-length)\n", s.stats.LongLines, maxLineLength)
+	}
+	if s.order.outOfOrder > 0 {
+		fmt.Fprintf(os.Stderr, "%d lines were out of order by more than %v\n", s.order.outOfOrder, opts.reorderTolerance)
+	}
+
+	// When a time range is given, the sparkline spans the whole range even if the log doesn't
+	firstTime, lastTime := binner.bounds()
+	if !opts.timeRange.Since.IsZero() {
+		firstTime = opts.timeRange.Since.UnixNano()
+	}
+	if !opts.timeRange.Until.IsZero() {
+		lastTime = opts.timeRange.Until.UnixNano()
+	}
+
+	firstTimestamp, lastTimestamp := binner.bounds()
+	if opts.bucketDuration > 0 {
+		grid := opts.bucketGrid()
+		first, last := grid.bucket(firstTime), grid.bucket(lastTime)
+		if count := last - first + 1; count > maxFixedBuckets {
+			return fmt.Errorf("the log spans %d buckets of %v, more than the %d that can be drawn; use a longer -bucket, or narrow the range with -since and -until", count, opts.bucketDuration, maxFixedBuckets)
+		}
+		// The rates are for the buckets that are drawn
+		report.addTimestamps(opts.displayTime(firstTimestamp), opts.displayTime(lastTimestamp), grid.start(first), grid.start(last+1)-1, binner.fixedBins(first, last))
+	} else {
+		report.addTimestamps(opts.displayTime(firstTimestamp), opts.displayTime(lastTimestamp), firstTime, lastTime, binner.bins(firstTime, lastTime))
+	}
+
+	switch {
+	case opts.bucketDuration > 0:
+		rows, labelWidth := []series{{binner: binner}}, 0
+		if s.series != nil {
+			rows = s.series.top(opts.topSeries)
+			labelWidth = seriesLabelWidth(rows)
+		}
+		fmt.Fprint(w, renderFixedBuckets(rows, labelWidth, firstTime, lastTime, opts.displayTime, terminalWidth, opts.look()))
+	case s.series != nil:
+		fmt.Fprint(w, renderSeries(s.series.top(opts.topSeries), opts.displayTime(firstTime), opts.displayTime(lastTime), firstTime, lastTime, opts.timeMarkerCount, terminalWidth, opts.look()))
+	default:
+		fmt.Fprint(w, renderSparkline(binner.levels(firstTime, lastTime, bucketCount), opts.displayTime(firstTime), opts.displayTime(lastTime), opts.timeMarkerCount, terminalWidth, opts.look()))
+	}
+	if opts.statsFormat != statsFormatNone {
+		return report.write(w, opts.statsFormat)
+	}
 
 	return nil
 }
 
+// renderSparkline draws the sparkline for the given bucket counts, surrounded by time markers that span the range
+// from firstTimestamp to lastTimestamp.
+func renderSparkline(logLineCountPerCharacter []float64, firstTimestamp, lastTimestamp time.Time, timeMarkerCount int, terminalWidth int, look sparklineLook) string {
+	sparkLines := look.rows(logLineCountPerCharacter, -1)
+	header, footer := renderHeaderAndFooter(firstTimestamp, lastTimestamp, timeMarkerCount, terminalWidth)
+
+	return header + strings.Join(sparkLines, "\n") + "\n" + footer
+}
+
+// detectWidth returns the width of the first of the file descriptors that's a terminal, or the value of the COLUMNS
+// environment variable if none of them is. It returns 0 if the width can't be found, e.g. when running under cron.
+func detectWidth(fds []int, getSize func(fd int) (width, height int, err error), columns string) int {
+	for _, fd := range fds {
+		if width, _, err := getSize(fd); err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(strings.TrimSpace(columns)); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+// printProgress displays how much of the input has been read, as a percentage if the total size is known and as a
+// byte count otherwise.
+func printProgress(bytesRead, totalBytes int64) {
+	if totalBytes > 0 {
+		fmt.Fprintf(os.Stderr, "\r%.f%%", math.Floor(100.0*float64(bytesRead)/float64(totalBytes)))
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%d MiB", bytesRead/unknownSizeReportInterval)
+	}
+}
+
+// stringsFlag is a flag that can be given more than once. Each value is appended to the list.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// isFlagSet reports whether the flag was given on the command line, as opposed to having its default value.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func exitWithErrorMessage(m string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, m, args...)
 	os.Exit(-1)
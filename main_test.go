@@ -2,12 +2,17 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"github.com/acj/krapslog/timefinder"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
-func Test_displaySparklineForLog(t *testing.T) {
-	lines := `Nov 23 06:26:40 ip-10-1-1-1 haproxy[20128]: 10.1.1.10:57305 [23/Nov/2019:06:26:40.781] public myapp/i-05fa49c0e7db8c328 0/0/0/78/78 206 913/458 - - ---- 9/9/6/0/0 0/0 {bytes=0-0} {||1|bytes 0-0/499704} "GET /2518cb13a48bdf53b2f936f44e7042a3cc7baa06 HTTP/1.1"
+const sampleLogLines = `Nov 23 06:26:40 ip-10-1-1-1 haproxy[20128]: 10.1.1.10:57305 [23/Nov/2019:06:26:40.781] public myapp/i-05fa49c0e7db8c328 0/0/0/78/78 206 913/458 - - ---- 9/9/6/0/0 0/0 {bytes=0-0} {||1|bytes 0-0/499704} "GET /2518cb13a48bdf53b2f936f44e7042a3cc7baa06 HTTP/1.1"
 Nov 23 06:26:41 ip-10-1-1-1 haproxy[20128]: 10.1.1.11:51819 [23/Nov/2019:06:26:41.780] public myapp/i-059c225b48702964a 0/0/0/80/80 200 802/142190 - - ---- 8/8/5/0/0 0/0 {} {||141752|} "GET /2043f2eb9e2691edcc0c8084d1ffce8bd70bc6e7 HTTP/1.1"
 Nov 23 06:26:42 ip-10-1-1-1 haproxy[20128]: 10.1.1.12:38870 [23/Nov/2019:06:26:42.773] public myapp/i-048088fd46abe7ed0 0/0/0/77/100 200 823/512174 - - ---- 8/8/5/0/0 0/0 {} {||511736|} "GET /eb59c0b5dad36f080f3d261c6257ce0e21ef1a01 HTTP/1.1"
 Nov 23 06:26:43 ip-10-1-1-1 haproxy[20128]: 10.1.1.13:35528 [23/Nov/2019:06:26:43.775] public myapp/i-05e9315b035d50f62 0/0/0/103/105 200 869/431481 - - ---- 8/8/1/0/0 0/0 {} {|||} "GET /164672c9d75c76a8fa237c24f9cbfd2222554f6d HTTP/1.1"
@@ -18,20 +23,22 @@ Nov 23 06:26:47 ip-10-1-1-1 haproxy[20128]: 10.1.1.11:51847 [23/Nov/2019:06:26:4
 Nov 23 06:26:48 ip-10-1-1-1 haproxy[20128]: 10.1.1.13:35554 [23/Nov/2019:06:26:48.866] public myapp/i-07f4205f35b4774b6 0/0/0/23/49 200 816/319662 - - ---- 5/5/3/0/0 0/0 {} {||319224|} "GET /b95db0578977cd32658fa28b386c0db67ab23ee7 HTTP/1.1"
 Nov 23 06:26:49 ip-10-1-1-1 haproxy[20128]: 10.1.1.12:38899 [23/Nov/2019:06:26:49.879] public myapp/i-08cb5309afd22e8c0 0/0/0/59/59 200 1000/112110 - - ---- 5/5/3/0/0 0/0 {} {||111672|} "GET /5314ca870ed0f5e48a71adca185e4ff7f1d9d80f HTTP/1.1"
 `
-	logFile := strings.NewReader(lines)
+
+func Test_displaySparklineForLog(t *testing.T) {
+	logFile := strings.NewReader(sampleLogLines)
 	output := &bytes.Buffer{}
-	displaySparkline(logFile, output, apacheCommonLogFormatDate, 10, false)
+	displaySparkline([]io.Reader{logFile}, output, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
 
-	expected := `                                                             Sat Nov 23 06:26:48
-                                                    Sat Nov 23 06:26:47        |
-                                           Sat Nov 23 06:26:46        |        |
-                                  Sat Nov 23 06:26:45        |        |        |
-                          Sat Nov 23 06:26:44       |        |        |        |
+	expected := `                                                             Sat Nov 23 06:26:49
+                                                    Sat Nov 23 06:26:48        |
+                                           Sat Nov 23 06:26:47        |        |
+                                  Sat Nov 23 06:26:46        |        |        |
+                          Sat Nov 23 06:26:45       |        |        |        |
                                             |       |        |        |        |
-█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁
+█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█
 |        |        |       |        |                                            
-|        |        |       |        Sat Nov 23 06:26:43                          
-|        |        |       Sat Nov 23 06:26:42                                   
+|        |        |       |        Sat Nov 23 06:26:44                          
+|        |        |       Sat Nov 23 06:26:43                                   
 |        |        Sat Nov 23 06:26:42                                           
 |        Sat Nov 23 06:26:41                                                    
 Sat Nov 23 06:26:40                                                             
@@ -50,3 +57,517 @@ got (%d bytes):
 		t.Errorf(format, len(expected), expected, len(actual), actual)
 	}
 }
+
+func Test_displaySparklineForMultipleLogs(t *testing.T) {
+	lines := strings.SplitAfter(sampleLogLines, "\n")
+	firstHalf := strings.Join(lines[:5], "")
+	secondHalf := strings.TrimSuffix(strings.Join(lines[5:], ""), "\n")
+
+	singleOutput := &bytes.Buffer{}
+	displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, singleOutput, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
+	multipleOutput := &bytes.Buffer{}
+	displaySparkline([]io.Reader{strings.NewReader(secondHalf), strings.NewReader(firstHalf)}, multipleOutput, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
+
+	if multipleOutput.String() != singleOutput.String() {
+		t.Errorf("output for multiple logs differs from output for a single log:\n%s\nvs\n%s", multipleOutput.String(), singleOutput.String())
+	}
+}
+
+func Test_displaySparklineWithHeight(t *testing.T) {
+	output := &bytes.Buffer{}
+	if err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 2, height: 2}); err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	// The markers' stems line up with the columns of both rows
+	expected := `                                                             Sat Nov 23 06:26:49
+                                                                               |
+█       █        █        █        █       █        █         █        █       █
+█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█
+|                                                                               
+Sat Nov 23 06:26:40                                                             
+`
+	if output.String() != expected {
+		t.Errorf("displaySparkline() = %q, want %q", output.String(), expected)
+	}
+}
+
+func Test_displaySparklineInBraille(t *testing.T) {
+	output := &bytes.Buffer{}
+	if err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 2, style: styleBraille}); err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	// Each column shows two buckets, so the first line is in the left half of the first column and the last line is in
+	// the right half of the last column
+	expected := `                                                             Sat Nov 23 06:26:49
+                                                                               |
+⣇⣀⣀⣀⣀⣀⣀⣀⣸⣀⣀⣀⣀⣀⣀⣀⣀⣸⣀⣀⣀⣀⣀⣀⣀⣀⣇⣀⣀⣀⣀⣀⣀⣀⣀⣇⣀⣀⣀⣀⣀⣀⣀⣇⣀⣀⣀⣀⣀⣀⣀⣀⣇⣀⣀⣀⣀⣀⣀⣀⣀⣀⣇⣀⣀⣀⣀⣀⣀⣀⣀⣇⣀⣀⣀⣀⣀⣀⣀⣸
+|                                                                               
+Sat Nov 23 06:26:40                                                             
+`
+	if output.String() != expected {
+		t.Errorf("displaySparkline() = %q, want %q", output.String(), expected)
+	}
+}
+
+func Test_displaySparklineWithWidth(t *testing.T) {
+	output := &bytes.Buffer{}
+	if err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{dateFormat: apacheCommonLogFormatDate, width: 20}); err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+	if want := "█▁█▁█▁█▁█▁█▁▁█▁█▁█▁█\n"; output.String() != want {
+		t.Errorf("displaySparkline() = %q, want %q", output.String(), want)
+	}
+}
+
+func Test_detectWidth(t *testing.T) {
+	const stdout, stderr = 1, 2
+	sizes := func(widths map[int]int) func(fd int) (int, int, error) {
+		return func(fd int) (int, int, error) {
+			if width, ok := widths[fd]; ok {
+				return width, 24, nil
+			}
+			return 0, 0, errors.New("inappropriate ioctl for device")
+		}
+	}
+	tests := []struct {
+		name    string
+		widths  map[int]int
+		columns string
+		want    int
+	}{
+		{"stdout is a terminal", map[int]int{stdout: 120, stderr: 100}, "90", 120},
+		{"only stderr is a terminal", map[int]int{stderr: 100}, "90", 100},
+		{"neither is a terminal", nil, "90", 90},
+		{"COLUMNS isn't a number", nil, "wide", 0},
+		{"nothing to go on", nil, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectWidth([]int{stdout, stderr}, sizes(tt.widths), tt.columns); got != tt.want {
+				t.Errorf("detectWidth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_options_bucketGrid(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Kathmandu was 5:30 ahead of UTC until 1986, and has been 5:45 ahead since
+	kathmandu, err := time.LoadLocation("Asia/Kathmandu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opts options
+		t    time.Time
+		want time.Time
+	}{
+		{"hours in UTC", options{bucketDuration: time.Hour}, time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC), time.Date(2019, time.November, 23, 6, 0, 0, 0, time.UTC)},
+		{"days in the winter", options{bucketDuration: 24 * time.Hour, displayLocation: newYork}, time.Date(2024, time.January, 11, 0, 30, 0, 0, newYork), time.Date(2024, time.January, 11, 0, 0, 0, 0, newYork)},
+		{"days in the summer", options{bucketDuration: 24 * time.Hour, displayLocation: newYork}, time.Date(2024, time.July, 11, 0, 30, 0, 0, newYork), time.Date(2024, time.July, 11, 0, 0, 0, 0, newYork)},
+		{"weeks", options{bucketDuration: 7 * 24 * time.Hour, displayLocation: newYork}, time.Date(2024, time.July, 11, 0, 30, 0, 0, newYork), time.Date(2024, time.July, 11, 0, 0, 0, 0, newYork)},
+		{"hours in the summer", options{bucketDuration: time.Hour, displayLocation: newYork}, time.Date(2024, time.July, 11, 23, 30, 0, 0, newYork), time.Date(2024, time.July, 11, 23, 0, 0, 0, newYork)},
+		{"hours after the clocks are set back", options{bucketDuration: time.Hour, displayLocation: newYork}, time.Date(2024, time.November, 3, 22, 30, 0, 0, newYork), time.Date(2024, time.November, 3, 22, 0, 0, 0, newYork)},
+		{"hours in a zone whose offset changed", options{bucketDuration: time.Hour, displayLocation: kathmandu}, time.Date(2024, time.July, 11, 10, 20, 0, 0, kathmandu), time.Date(2024, time.July, 11, 10, 0, 0, 0, kathmandu)},
+		{"durations that don't divide a day", options{bucketDuration: 7 * time.Minute}, time.Date(1970, time.January, 1, 0, 15, 0, 0, time.UTC), time.Date(1970, time.January, 1, 0, 14, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := tt.opts.bucketGrid()
+			if got := time.Unix(0, grid.start(grid.bucket(tt.t.UnixNano()))); !got.Equal(tt.want) {
+				t.Errorf("bucket of %v starts at %v, want %v", tt.t, got.In(tt.want.Location()), tt.want)
+			}
+		})
+	}
+}
+
+func Test_displaySparklineWithFixedBuckets(t *testing.T) {
+	t.Run("wraps the buckets into rows", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		if err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{dateFormat: apacheCommonLogFormatDate, bucketDuration: 3 * time.Second, width: 25}); err != nil {
+			t.Fatalf("displaySparkline() error = %v", err)
+		}
+
+		// The buckets start at multiples of 3s from the epoch, and each row has five of them
+		want := "Sat Nov 23 06:26:30    ▁█\n" +
+			"Sat Nov 23 06:26:45 █▁   \n"
+		if output.String() != want {
+			t.Errorf("displaySparkline() = %q, want %q", output.String(), want)
+		}
+	})
+
+	t.Run("starts the buckets on the day or hour in the display location", func(t *testing.T) {
+		newYork, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Fatal(err)
+		}
+		kathmandu, err := time.LoadLocation("Asia/Kathmandu")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			name            string
+			lines           []string
+			bucketDuration  time.Duration
+			displayLocation *time.Location
+			want            string
+		}{
+			{
+				// The second and third lines are just after midnight in New York, when it's 4 hours behind UTC
+				name:            "days in the summer",
+				lines:           []string{"[10/Jul/2024:12:00:00.000]", "[11/Jul/2024:04:30:00.000]", "[11/Jul/2024:04:40:00.000]"},
+				bucketDuration:  24 * time.Hour,
+				displayLocation: newYork,
+				want:            "Mon Jul 8    ▁█               \n",
+			},
+			{
+				name:            "hours in a zone whose offset changed",
+				lines:           []string{"[11/Jul/2024:04:20:00.000]", "[11/Jul/2024:05:00:00.000]", "[11/Jul/2024:05:20:00.000]"},
+				bucketDuration:  time.Hour,
+				displayLocation: kathmandu,
+				want:            "Thu Jul 11 00:00           █▁\n",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				output := &bytes.Buffer{}
+				opts := options{dateFormat: apacheCommonLogFormatDate, bucketDuration: tt.bucketDuration, displayLocation: tt.displayLocation, width: 30}
+				if err := displaySparkline([]io.Reader{strings.NewReader(strings.Join(tt.lines, "\n"))}, output, opts); err != nil {
+					t.Fatalf("displaySparkline() error = %v", err)
+				}
+				if output.String() != tt.want {
+					t.Errorf("displaySparkline() = %q, want %q", output.String(), tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("with too many buckets, returns an error", func(t *testing.T) {
+		err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, &bytes.Buffer{}, options{dateFormat: apacheCommonLogFormatDate, bucketDuration: time.Microsecond})
+		if err == nil || !strings.Contains(err.Error(), "buckets") {
+			t.Errorf("displaySparkline() error = %v, want an error about the number of buckets", err)
+		}
+	})
+}
+
+func Test_displaySparklineForTimeRange(t *testing.T) {
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 2, since: "23/Nov/2019:06:26:45.000", until: "2019-11-23T06:26:54Z"})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	// Lines from 06:26:45 through 06:26:49 fall in the first half of the range, and nothing is logged in the second half
+	expected := `                                                             Sat Nov 23 06:26:53
+                                                                               |
+` + "▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█" + strings.Repeat("▁", 36) + `
+|                                                                               
+Sat Nov 23 06:26:45                                                             
+`
+	if actual := output.String(); actual != expected {
+		t.Errorf("incorrect output\n\nwanted:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func Test_displaySparklineForTimeRangeInDetectedFormat(t *testing.T) {
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{dateFormat: timefinder.AutoFormat, timeMarkerCount: 2, inputLocation: time.UTC, since: "23/Nov/2019:06:26:45.000", until: "2019-11-23T06:26:54Z"})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+	if !strings.Contains(output.String(), "Sat Nov 23 06:26:45") {
+		t.Errorf("displaySparkline() didn't start at -since\n%s", output.String())
+	}
+
+	err = displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, io.Discard, options{dateFormat: timefinder.AutoFormat, inputLocation: time.UTC, since: "auto"})
+	if err == nil {
+		t.Error("displaySparkline() expected an error for -since auto but didn't get one")
+	}
+}
+
+func Test_displaySparklineForTimeRangeWithoutYear(t *testing.T) {
+	tests := []struct {
+		name string
+		opts options
+	}{
+		{"with the year", options{year: 2019}},
+		{"with the reference time", options{referenceTime: time.Date(2019, time.November, 24, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The -since value gets its year the same way as the log's timestamps, rather than from the current time
+			opts := tt.opts
+			opts.dateFormat, opts.since, opts.statsFormat = "Jan _2 15:04:05", "Nov 23 06:26:44", statsFormatText
+			output := &bytes.Buffer{}
+			if err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, opts); err != nil {
+				t.Fatalf("displaySparkline() error = %v", err)
+			}
+			if want := "out of range lines:  4\n"; !strings.Contains(output.String(), want) {
+				t.Errorf("displaySparkline() = %q, want it to contain %q", output.String(), want)
+			}
+		})
+	}
+}
+
+func Test_parseTimeRange(t *testing.T) {
+	timeFinder, err := timefinder.NewTimeFinder(apacheCommonLogFormatDate)
+	if err != nil {
+		t.Fatalf("NewTimeFinder() error = %v", err)
+	}
+	if _, err := parseTimeRange("2019-11-23T07:00:00Z", "2019-11-23T06:00:00Z", timeFinder, time.Now()); err == nil {
+		t.Error("parseTimeRange: expected an error for an inverted range but didn't get one")
+	}
+	if _, err := parseTimeRange("garbage", "", timeFinder, time.Now()); err == nil {
+		t.Error("parseTimeRange: expected an error for an invalid value but didn't get one")
+	}
+}
+
+func Test_displaySparklineForYearlessFormat(t *testing.T) {
+	var withYear, withoutYear strings.Builder
+	for i, t := range []string{"06:26:40", "06:26:41", "06:26:41", "06:26:45", "06:26:49"} {
+		fmt.Fprintf(&withYear, "2019-11-23T%sZ host app[%d]: hi mom\n", t, i)
+		fmt.Fprintf(&withoutYear, "Nov 23 %s host app[%d]: hi mom\n", t, i)
+	}
+
+	expected := &bytes.Buffer{}
+	displaySparkline([]io.Reader{strings.NewReader(withYear.String())}, expected, options{dateFormat: time.RFC3339, timeMarkerCount: 10})
+
+	actual := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(withoutYear.String())}, actual, options{dateFormat: "Jan _2 15:04:05", timeMarkerCount: 10, year: 2019})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	if actual.String() != expected.String() {
+		t.Errorf("incorrect output\n\nwanted:\n%s\ngot:\n%s", expected.String(), actual.String())
+	}
+}
+
+func Test_displaySparklineForTimeZones(t *testing.T) {
+	utcOutput := &bytes.Buffer{}
+	displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, utcOutput, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
+
+	// The log is written an hour ahead of UTC and displayed five hours behind it
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{
+		dateFormat:      apacheCommonLogFormatDate,
+		timeMarkerCount: 10,
+		inputLocation:   time.FixedZone("UTC+1", 60*60),
+		displayLocation: time.FixedZone("UTC-5", -5*60*60),
+	})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	if expected := strings.ReplaceAll(utcOutput.String(), "06:26:", "00:26:"); output.String() != expected {
+		t.Errorf("incorrect output\n\nwanted:\n%s\ngot:\n%s", expected, output.String())
+	}
+}
+
+func Test_displaySparklineForOutOfOrderLog(t *testing.T) {
+	lines := strings.SplitAfter(sampleLogLines, "\n")
+	shuffled := []string{lines[3], lines[0], lines[9], lines[1], lines[2], lines[8], lines[4], lines[6], lines[5], lines[7]}
+
+	sortedOutput := &bytes.Buffer{}
+	displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, sortedOutput, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
+	shuffledOutput := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(strings.Join(shuffled, ""))}, shuffledOutput, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	if shuffledOutput.String() != sortedOutput.String() {
+		t.Errorf("output for an out-of-order log differs from output for a sorted log:\n%s\nvs\n%s", shuffledOutput.String(), sortedOutput.String())
+	}
+}
+
+func Test_displaySparklineForLongLines(t *testing.T) {
+	lines := strings.SplitAfter(sampleLogLines, "\n")
+	// A line that's too long for bufio.Scanner's default buffer shouldn't stop the scan
+	lines[4] = strings.TrimSuffix(lines[4], "\n") + strings.Repeat(" padding", 10000) + "\n"
+
+	expected := &bytes.Buffer{}
+	displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, expected, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
+	actual := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(strings.Join(lines, ""))}, actual, options{dateFormat: apacheCommonLogFormatDate, timeMarkerCount: 10})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	if actual.String() != expected.String() {
+		t.Errorf("incorrect output\n\nwanted:\n%s\ngot:\n%s", expected.String(), actual.String())
+	}
+}
+
+func Test_displaySparklineForFailingReader(t *testing.T) {
+	r := io.MultiReader(strings.NewReader(sampleLogLines), iotest.ErrReader(errors.New("disk on fire")))
+	err := displaySparkline([]io.Reader{r}, &bytes.Buffer{}, options{dateFormat: apacheCommonLogFormatDate})
+	if err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("displaySparkline() error = %v, want the reader's error", err)
+	}
+}
+
+func Test_displaySparklineWithFilters(t *testing.T) {
+	// The first and last lines are selected by their paths. The eighth line is also a 206 response, but it's excluded by
+	// its server.
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{
+		dateFormat:      apacheCommonLogFormatDate,
+		includePatterns: []string{"GET /2518cb", `GET /5314ca`, ` 206 `},
+		excludePatterns: []string{`i-0aa5664\d+`},
+		statsFormat:     statsFormatText,
+	})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	if !strings.Contains(output.String(), "matched lines:       2\nfiltered lines:      8\n") {
+		t.Errorf("incorrect output\n%s", output.String())
+	}
+	if sparkline, _, _ := strings.Cut(output.String(), "\n"); sparkline != "█"+strings.Repeat("▁", 78)+"█" {
+		t.Errorf("incorrect sparkline %q", sparkline)
+	}
+}
+
+func Test_displaySparklineSplitBySeries(t *testing.T) {
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{
+		dateFormat:      apacheCommonLogFormatDate,
+		splitPattern:    `(\d{3}) \d+/\d+ - -`,
+		topSeries:       1,
+		timeMarkerCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	// The 206 responses are the first and eighth lines
+	expected := `                                                             Sat Nov 23 06:26:49
+                                                                               |
+200   ▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█
+other █▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁
+      |                                                                         
+      Sat Nov 23 06:26:40                                                       
+`
+	if actual := output.String(); actual != expected {
+		t.Errorf("incorrect output\n\nwanted:\n'%s'\n\ngot:\n'%s'", expected, actual)
+	}
+}
+
+func Test_displaySparklineForValues(t *testing.T) {
+	// The total response times are 78, 80, 100, 105, 73, 167, 171, 28, 49, and 59ms
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, output, options{
+		dateFormat:   apacheCommonLogFormatDate,
+		valuePattern: `\d+/\d+/\d+/\d+/(\d+) `,
+		aggregation:  aggregation{kind: aggregateMax},
+	})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+	if expected := "▄▁▁▁▁▁▁▁▄▁▁▁▁▁▁▁▁▅▁▁▁▁▁▁▁▁▅▁▁▁▁▁▁▁▁▄▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▂▁▁▁▁▁▁▁▁▃▁▁▁▁▁▁▁▃\n"; output.String() != expected {
+		t.Errorf("incorrect output\n\nwanted:\n'%s'\n\ngot:\n'%s'", expected, output.String())
+	}
+
+	err = displaySparkline([]io.Reader{strings.NewReader(sampleLogLines)}, &bytes.Buffer{}, options{
+		dateFormat:   apacheCommonLogFormatDate,
+		valuePattern: `took (\d+)ms`,
+	})
+	if err == nil {
+		t.Errorf("displaySparkline() didn't return an error for a log without values")
+	}
+}
+
+const sampleJSONLines = `{"time": "2019-11-23T06:26:40.781Z", "level": "info", "http": {"status": 206, "duration_ms": 78}}
+{"time": "2019-11-23T06:26:41.780Z", "level": "info", "http": {"status": 200, "duration_ms": 80}}
+{"time": "2019-11-23T06:26:42.773Z", "level": "info", "http": {"status": 200, "duration_ms": 100}}
+{"time": "2019-11-23T06:26:43.775Z", "level": "debug", "msg": "cache miss at 2019-11-23T06:26:49Z"}
+{"time": "2019-11-23T06:26:44.808Z", "level": "info", "http": {"status": 200, "duration_ms": 73}}
+{"time": "2019-11-23T06:26:45.727Z", "level": "info", "http": {"status": 200, "duration_ms": 167}}
+{"time": "2019-11-23T06:26:46.730Z", "level": "info", "http": {"status": 200, "duration_ms": 171}}
+{"time": "2019-11-23T06:26:47.886Z", "level": "info", "http": {"status": 206, "duration_ms": 28}}
+{"time": "2019-11-23T06:26:48.866Z", "level": "info", "http": {"status": 200, "duration_ms": 49}}
+{"time": "2019-11-23T06:26:49.879Z", "level": "info", "http": {"status": 200, "duration_ms": 59}}
+`
+
+func Test_displaySparklineForJSONLines(t *testing.T) {
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(sampleJSONLines)}, output, options{
+		dateFormat:      timefinder.AutoFormat,
+		fieldParser:     timefinder.ParseJSON,
+		timeField:       "time",
+		excludePatterns: []string{"level=debug"},
+		splitPattern:    "http.status",
+		topSeries:       5,
+		statsFormat:     statsFormatText,
+	})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+	// The debug line is filtered out, so it doesn't show up in the "other" series
+	expected := `200 ▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁█
+206 █▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁
+total lines:         10
+matched lines:       9
+filtered lines:      1
+`
+	if !strings.HasPrefix(output.String(), expected) {
+		t.Errorf("incorrect output\n\nwanted:\n'%s'\n\ngot:\n'%s'", expected, output.String())
+	}
+
+	output.Reset()
+	err = displaySparkline([]io.Reader{strings.NewReader(sampleJSONLines)}, output, options{
+		dateFormat:   time.RFC3339Nano,
+		fieldParser:  timefinder.ParseJSON,
+		timeField:    "time",
+		valuePattern: "http.duration_ms",
+		aggregation:  aggregation{kind: aggregateMax},
+	})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+	// Like Test_displaySparklineForValues, but the fourth line doesn't have a value
+	if expected := "▄▁▁▁▁▁▁▁▄▁▁▁▁▁▁▁▁▅▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▄▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▂▁▁▁▁▁▁▁▁▃▁▁▁▁▁▁▁▃\n"; output.String() != expected {
+		t.Errorf("incorrect output\n\nwanted:\n'%s'\n\ngot:\n'%s'", expected, output.String())
+	}
+}
+
+func Test_displaySparklineForLogfmt(t *testing.T) {
+	lines := `ts=2019-11-23T06:26:40.781Z level=info status=206 msg="GET /2518cb"
+ts=2019-11-23T06:26:41.780Z level=info status=200 msg="GET /2043f2"
+ts=2019-11-23T06:26:42.773Z level=debug msg="cache miss"
+ts=2019-11-23T06:26:43.775Z level=info status=200 msg="GET /164672"
+starting worker pool
+ts=2019-11-23T06:26:44.808Z level=info status=206 msg="GET /e3b526"
+`
+	output := &bytes.Buffer{}
+	err := displaySparkline([]io.Reader{strings.NewReader(lines)}, output, options{
+		dateFormat:      timefinder.AutoFormat,
+		fieldParser:     timefinder.ParseLogfmt,
+		timeField:       "ts",
+		includePatterns: []string{"level=info"},
+		splitPattern:    "status",
+		topSeries:       1,
+		statsFormat:     statsFormatText,
+	})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+	// The debug line is filtered out and the line that isn't logfmt is unmatched
+	for _, want := range []string{
+		"200   ▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁\n",
+		"other █▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█\n",
+		"matched lines:       4\nfiltered lines:      1\nunmatched lines:     1\n",
+		"unmatched samples:\n  starting worker pool\n",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("output doesn't contain %q\n%s", want, output.String())
+		}
+	}
+}
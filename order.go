@@ -0,0 +1,32 @@
+package main
+
+// orderChecker counts the timestamps that are out of order, meaning that they're earlier than the latest timestamp
+// before them by more than the tolerance. Logs merged from several hosts are often slightly out of order, and the
+// tolerance keeps that from being reported. Timestamps are in nanoseconds since the Unix epoch.
+type orderChecker struct {
+	tolerance  int64
+	latest     int64
+	started    bool
+	outOfOrder int
+}
+
+func newOrderChecker(tolerance int64) *orderChecker {
+	return &orderChecker{tolerance: tolerance}
+}
+
+func (c *orderChecker) check(lineUnixNanos int64) {
+	if !c.started || lineUnixNanos > c.latest {
+		c.latest = lineUnixNanos
+		c.started = true
+		return
+	}
+	if c.latest-lineUnixNanos > c.tolerance {
+		c.outOfOrder++
+	}
+}
+
+// startInput forgets the latest timestamp so that the next input isn't compared with the previous one. Inputs don't
+// have to be given in chronological order.
+func (c *orderChecker) startInput() {
+	c.started = false
+}
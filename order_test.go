@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_orderChecker(t *testing.T) {
+	sec := func(seconds int64) int64 {
+		return seconds * int64(time.Second)
+	}
+	tests := []struct {
+		name       string
+		tolerance  time.Duration
+		inputs     [][]int64
+		outOfOrder int
+	}{
+		{"in order", 0, [][]int64{{sec(1), sec(2), sec(2), sec(3)}}, 0},
+		{"out of order", 0, [][]int64{{sec(1), sec(3), sec(2), sec(4)}}, 1},
+		{"compares with the latest timestamp", 0, [][]int64{{sec(5), sec(1), sec(2), sec(3)}}, 3},
+		{"within the tolerance", time.Second, [][]int64{{sec(1), sec(3), sec(2), sec(4)}}, 0},
+		{"beyond the tolerance", time.Second, [][]int64{{sec(1), sec(4), sec(2), sec(5)}}, 1},
+		{"inputs in reverse order", 0, [][]int64{{sec(3), sec(4)}, {sec(1), sec(2)}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newOrderChecker(int64(tt.tolerance))
+			for _, input := range tt.inputs {
+				c.startInput()
+				for _, timestamp := range input {
+					c.check(timestamp)
+				}
+			}
+			if c.outOfOrder != tt.outOfOrder {
+				t.Errorf("outOfOrder = %v, want %v", c.outOfOrder, tt.outOfOrder)
+			}
+		})
+	}
+}
@@ -3,45 +3,69 @@ package main
 import (
 	"io"
 	"math"
-	"os"
+	"sync"
 )
 
-type ProgressReader struct {
-	io.Reader
-	currentOffset int64
-	totalBytes    int64
-	progressFunc  func(currentProgress float64)
+// unknownSizeReportInterval is how often, in bytes, progress is reported when the total size of the input is unknown.
+const unknownSizeReportInterval = 1 << 20
+
+// progressTracker accumulates the number of bytes read across a set of inputs and decides when progress should be
+// reported. It's safe for concurrent use.
+type progressTracker struct {
+	mu           sync.Mutex
+	bytesRead    int64
+	totalBytes   int64
+	progressFunc func(bytesRead, totalBytes int64)
 }
 
-func NewProgressReader(r io.Reader, progressFunc func(float64)) (*ProgressReader, error) {
-	pr := &ProgressReader{
-		Reader:        r,
-		currentOffset: 0,
-		totalBytes:    0,
-		progressFunc:  progressFunc,
+func newProgressTracker(totalBytes int64, progressFunc func(bytesRead, totalBytes int64)) *progressTracker {
+	return &progressTracker{
+		bytesRead:    0,
+		totalBytes:   totalBytes,
+		progressFunc: progressFunc,
 	}
+}
 
-	if r, ok := r.(*os.File); ok {
-		stat, err := r.Stat()
-		if err != nil {
-			return nil, err
-		}
-		pr.totalBytes = stat.Size()
+// advance records that n more bytes have been read. If the total size is known, the progress function is invoked
+// whenever the whole-number percentage changes. Otherwise, it's invoked every unknownSizeReportInterval bytes.
+func (t *progressTracker) advance(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var lastProgress, nextProgress float64
+	if t.totalBytes > 0 {
+		lastProgress = math.Floor(100.0 * float64(t.bytesRead) / float64(t.totalBytes))
+		nextProgress = math.Floor(100.0 * (float64(t.bytesRead) + float64(n)) / float64(t.totalBytes))
+	} else {
+		lastProgress = math.Floor(float64(t.bytesRead) / unknownSizeReportInterval)
+		nextProgress = math.Floor((float64(t.bytesRead) + float64(n)) / unknownSizeReportInterval)
 	}
 
-	return pr, nil
+	t.bytesRead += int64(n)
+
+	if nextProgress != lastProgress && t.progressFunc != nil {
+		t.progressFunc(t.bytesRead, t.totalBytes)
+	}
 }
 
-func (p *ProgressReader) Read(buf []byte) (int, error) {
-	n, err := p.Reader.Read(buf)
+// ProgressReader counts the bytes read from a reader towards the progress of its tracker. Several ProgressReaders can
+// share a tracker so that progress is reported across all of the inputs rather than for each one.
+type ProgressReader struct {
+	io.Reader
+	tracker *progressTracker
+}
 
-	lastProgressPercent := math.Floor(100.0 * float64(p.currentOffset) / float64(p.totalBytes))
-	nextProgressPercent := math.Floor(100.0 * (float64(p.currentOffset) + float64(n)) / float64(p.totalBytes))
-	if p.totalBytes > 0 && nextProgressPercent != lastProgressPercent {
-		p.progressFunc(nextProgressPercent)
+func NewProgressReader(r io.Reader, tracker *progressTracker) *ProgressReader {
+	return &ProgressReader{
+		Reader:  r,
+		tracker: tracker,
 	}
+}
+
+func (p *ProgressReader) Read(buf []byte) (int, error) {
+	n, err := p.Reader.Read(buf)
 
-	p.currentOffset += int64(n)
+	p.tracker.advance(n)
 
 	return n, err
 }
@@ -8,38 +8,31 @@ import (
 )
 
 func TestNewProgressReader(t *testing.T) {
+	tracker := newProgressTracker(0, nil)
 	type args struct {
-		r            io.Reader
-		progressFunc func(float64)
+		r       io.Reader
+		tracker *progressTracker
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    *ProgressReader
-		wantErr bool
+		name string
+		args args
+		want *ProgressReader
 	}{
 		{
 			name: "",
 			args: args{
-				r:            strings.NewReader("one\ntwo\nthree\n"),
-				progressFunc: nil,
+				r:       strings.NewReader("one\ntwo\nthree\n"),
+				tracker: tracker,
 			},
 			want: &ProgressReader{
 				strings.NewReader("one\ntwo\nthree\n"),
-				0,
-				0,
-				nil,
+				tracker,
 			},
-			wantErr: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewProgressReader(tt.args.r, tt.args.progressFunc)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("NewProgressReader() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
+			got := NewProgressReader(tt.args.r, tt.args.tracker)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewProgressReader() got = %v, want %v", got, tt.want)
 			}
@@ -48,18 +41,20 @@ func TestNewProgressReader(t *testing.T) {
 }
 
 func TestProgressReader_Read(t *testing.T) {
-	t.Run("when we don't know the overall size, it doesn't invoke the callback function", func(t *testing.T) {
+	t.Run("when we don't know the overall size and haven't read much, it doesn't invoke the callback function", func(t *testing.T) {
 		called := false
 		pr := &ProgressReader{
 			strings.NewReader("hi mom"),
-			0,
-			0,
-			func(float64) {
-				called = true
+			&progressTracker{
+				bytesRead:  0,
+				totalBytes: 0,
+				progressFunc: func(int64, int64) {
+					called = true
+				},
 			},
 		}
 
-		var buf []byte
+		buf := make([]byte, 6)
 		_, err := pr.Read(buf)
 		if err != nil {
 			t.Fatalf("failed to read: %v", err)
@@ -69,14 +64,39 @@ func TestProgressReader_Read(t *testing.T) {
 		}
 	})
 
+	t.Run("when we don't know the overall size, it invokes the callback function after each reporting interval", func(t *testing.T) {
+		var actualBytesRead int64
+		pr := &ProgressReader{
+			strings.NewReader("hi mom"),
+			&progressTracker{
+				bytesRead:  unknownSizeReportInterval - 1,
+				totalBytes: 0,
+				progressFunc: func(bytesRead, totalBytes int64) {
+					actualBytesRead = bytesRead
+				},
+			},
+		}
+
+		buf := make([]byte, 6)
+		_, err := pr.Read(buf)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if actualBytesRead != unknownSizeReportInterval+5 {
+			t.Errorf("wrong byte count: got %d but want %d", actualBytesRead, unknownSizeReportInterval+5)
+		}
+	})
+
 	t.Run("when there's no change in percentage read, it doesn't invoke the callback function", func(t *testing.T) {
 		called := false
 		pr := &ProgressReader{
 			strings.NewReader("hi mom"),
-			999990,
-			1000000,
-			func(float64) {
-				called = true
+			&progressTracker{
+				bytesRead:  999990,
+				totalBytes: 1000000,
+				progressFunc: func(int64, int64) {
+					called = true
+				},
 			},
 		}
 
@@ -90,16 +110,19 @@ func TestProgressReader_Read(t *testing.T) {
 		}
 	})
 
-	t.Run("when there is a change in percentage read, it invokes the callback function with the correct percentage", func(t *testing.T) {
+	t.Run("when there is a change in percentage read, it invokes the callback function with the correct progress", func(t *testing.T) {
 		called := false
-		actualPercentage := -1.0
+		var actualBytesRead, actualTotalBytes int64
 		pr := &ProgressReader{
 			strings.NewReader("hi mom"),
-			4,
-			10,
-			func(p float64) {
-				called = true
-				actualPercentage = p
+			&progressTracker{
+				bytesRead:  4,
+				totalBytes: 10,
+				progressFunc: func(bytesRead, totalBytes int64) {
+					called = true
+					actualBytesRead = bytesRead
+					actualTotalBytes = totalBytes
+				},
 			},
 		}
 
@@ -114,9 +137,24 @@ func TestProgressReader_Read(t *testing.T) {
 		if n != 6 {
 			t.Errorf("read error: got %d bytes but want %d", n, 6)
 		}
-		expectedPercentage := 100.0
-		if actualPercentage != expectedPercentage {
-			t.Errorf("wrong percentage: got %f but want %f", actualPercentage, expectedPercentage)
+		if actualBytesRead != 10 || actualTotalBytes != 10 {
+			t.Errorf("wrong progress: got %d/%d but want %d/%d", actualBytesRead, actualTotalBytes, 10, 10)
+		}
+	})
+
+	t.Run("when readers share a tracker, it reports progress across all of them", func(t *testing.T) {
+		var actualBytesRead int64
+		tracker := newProgressTracker(12, func(bytesRead, totalBytes int64) {
+			actualBytesRead = bytesRead
+		})
+		first := NewProgressReader(strings.NewReader("hi mom"), tracker)
+		second := NewProgressReader(strings.NewReader("hi dad"), tracker)
+
+		if _, err := io.ReadAll(io.MultiReader(first, second)); err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if actualBytesRead != 12 {
+			t.Errorf("wrong byte count: got %d but want %d", actualBytesRead, 12)
 		}
 	})
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// sketchRelativeAccuracy is how far, relative to the true value, a quantile estimated by a quantileSketch can be
+	// from the value that was actually at that rank.
+	sketchRelativeAccuracy = 0.01
+	// maxSketchBins is the number of bins that each sign of value can use in a quantileSketch. If the values span too
+	// many orders of magnitude to fit, the bins of the values closest to zero are combined, which makes the estimates
+	// of those values less accurate.
+	maxSketchBins = 2048
+)
+
+var (
+	sketchGamma    = (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+// quantileSketch estimates quantiles of a set of values without storing them. Values are counted in bins whose
+// boundaries grow geometrically, so every value in a bin is within sketchRelativeAccuracy of the bin's midpoint and the
+// number of bins depends on the range of the values rather than on how many there are. Sketches can be merged.
+type quantileSketch struct {
+	count    float64
+	zeros    float64
+	positive map[int]float64
+	negative map[int]float64
+}
+
+func newQuantileSketch() *quantileSketch {
+	return &quantileSketch{
+		positive: make(map[int]float64),
+		negative: make(map[int]float64),
+	}
+}
+
+func (s *quantileSketch) add(value float64) {
+	s.count++
+	switch {
+	case value > 0:
+		addToSketchBins(s.positive, sketchBin(value), 1)
+	case value < 0:
+		addToSketchBins(s.negative, sketchBin(-value), 1)
+	default:
+		s.zeros++
+	}
+}
+
+func (s *quantileSketch) merge(other *quantileSketch) {
+	s.count += other.count
+	s.zeros += other.zeros
+	for bin, count := range other.positive {
+		addToSketchBins(s.positive, bin, count)
+	}
+	for bin, count := range other.negative {
+		addToSketchBins(s.negative, bin, count)
+	}
+}
+
+// quantile returns an estimate of the value at quantile q, which is between 0 and 1. It returns 0 if the sketch is
+// empty.
+func (s *quantileSketch) quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+
+	rank := q * (s.count - 1)
+	var seen float64
+	// Negative values come first, starting with the one furthest from zero
+	negativeBins := sortedSketchBins(s.negative)
+	for i := len(negativeBins) - 1; i >= 0; i-- {
+		seen += s.negative[negativeBins[i]]
+		if seen > rank {
+			return -sketchBinValue(negativeBins[i])
+		}
+	}
+	seen += s.zeros
+	if seen > rank {
+		return 0
+	}
+	positiveBins := sortedSketchBins(s.positive)
+	for _, bin := range positiveBins {
+		seen += s.positive[bin]
+		if seen > rank {
+			return sketchBinValue(bin)
+		}
+	}
+	// Only reached if q is greater than 1
+	if len(positiveBins) == 0 {
+		return 0
+	}
+	return sketchBinValue(positiveBins[len(positiveBins)-1])
+}
+
+// sketchBin returns the bin that a positive value is counted in.
+func sketchBin(value float64) int {
+	return int(math.Ceil(math.Log(value) / sketchLogGamma))
+}
+
+// sketchBinValue returns the value that represents a bin, which is within sketchRelativeAccuracy of every value in it.
+func sketchBinValue(bin int) float64 {
+	return 2 * math.Pow(sketchGamma, float64(bin)) / (sketchGamma + 1)
+}
+
+// addToSketchBins adds count values to a bin. If that makes too many bins, the two bins closest to zero are combined.
+func addToSketchBins(bins map[int]float64, bin int, count float64) {
+	bins[bin] += count
+	if len(bins) <= maxSketchBins {
+		return
+	}
+
+	sorted := sortedSketchBins(bins)
+	bins[sorted[1]] += bins[sorted[0]]
+	delete(bins, sorted[0])
+}
+
+func sortedSketchBins(bins map[int]float64) []int {
+	sorted := make([]int, 0, len(bins))
+	for bin := range bins {
+		sorted = append(sorted, bin)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
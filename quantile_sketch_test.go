@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_quantileSketch(t *testing.T) {
+	tests := []struct {
+		name   string
+		values func(i int) float64
+		q      float64
+		want   float64
+	}{
+		{"median", func(i int) float64 { return float64(i + 1) }, 0.5, 500},
+		{"p99", func(i int) float64 { return float64(i + 1) }, 0.99, 990},
+		{"minimum", func(i int) float64 { return float64(i + 1) }, 0, 1},
+		{"maximum", func(i int) float64 { return float64(i + 1) }, 1, 1000},
+		{"negative values", func(i int) float64 { return -float64(i + 1) }, 0.1, -900},
+		{"zeros", func(i int) float64 { return float64(i%2) * 100 }, 0.25, 0},
+		{"small values", func(i int) float64 { return float64(i+1) / 1e6 }, 0.5, 500e-6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newQuantileSketch()
+			for i := 0; i < 1000; i++ {
+				s.add(tt.values(i))
+			}
+			if got := s.quantile(tt.q); math.Abs(got-tt.want) > math.Abs(tt.want)*sketchRelativeAccuracy {
+				t.Errorf("quantile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_quantileSketchEmpty(t *testing.T) {
+	if got := newQuantileSketch().quantile(0.5); got != 0 {
+		t.Errorf("quantile() = %v, want 0", got)
+	}
+}
+
+func Test_quantileSketchMerge(t *testing.T) {
+	all, first, second := newQuantileSketch(), newQuantileSketch(), newQuantileSketch()
+	for i := 0; i < 1000; i++ {
+		value := float64(i*i) - 5000
+		all.add(value)
+		if i%3 == 0 {
+			first.add(value)
+		} else {
+			second.add(value)
+		}
+	}
+	first.merge(second)
+
+	for _, q := range []float64{0, 0.01, 0.5, 0.9, 1} {
+		if got, want := first.quantile(q), all.quantile(q); got != want {
+			t.Errorf("quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func Test_quantileSketchBinLimit(t *testing.T) {
+	s := newQuantileSketch()
+	for exponent := -300; exponent <= 300; exponent++ {
+		s.add(math.Pow(10, float64(exponent)))
+	}
+	if len(s.positive) > maxSketchBins {
+		t.Errorf("len(positive) = %v, want at most %v", len(s.positive), maxSketchBins)
+	}
+	// The largest values keep their accuracy
+	if got, want := s.quantile(1), 1e300; math.Abs(got-want) > want*sketchRelativeAccuracy {
+		t.Errorf("quantile(1) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/acj/krapslog/timefinder"
+	"io"
+	"math"
+	"sync"
+)
+
+// minChunkSize is the smallest part of an input that's scanned on its own. Splitting smaller inputs isn't worth the
+// overhead.
+const minChunkSize = 4 << 20
+
+// scanner finds the timestamp in each line of the inputs and counts it.
+type scanner struct {
+	timeFinder *timefinder.TimeFinder
+	binner     *streamingBinner
+	order      *orderChecker
+	tracker    *progressTracker
+	stats      timefinder.ScanStats
+	// splitter and series count each line's timestamp toward a series as well. They're nil unless the lines are split.
+	splitter *seriesSplitter
+	series   *seriesBinners
+	// values finds the value of each line for binners that aggregate values. It's nil if they only count lines.
+	values *valueExtractor
+	// parallelism is the number of goroutines that scan each input. Inputs are only split if they're uncompressed
+	// regular files and their timestamps can be interpreted without the lines before them.
+	parallelism int
+}
+
+func (s *scanner) scan(r io.Reader) error {
+	if s.parallelism > 1 && s.timeFinder.IsContextFree() {
+		if readerAt, size, ok := seekableInput(r); ok {
+			chunks, err := splitIntoChunks(readerAt, size, s.parallelism)
+			if err != nil {
+				return fmt.Errorf("failed to read log: %v", err)
+			}
+			if len(chunks) > 1 {
+				return s.scanChunks(chunks)
+			}
+		}
+	}
+
+	if s.tracker != nil {
+		r = NewProgressReader(r, s.tracker)
+	}
+	// Decompress after tracking progress so that progress is measured against the size of the files on disk
+	r, err := decompressIfNeeded(r)
+	if err != nil {
+		return fmt.Errorf("failed to read log: %v", err)
+	}
+
+	s.order.startInput()
+	stats, err := s.timeFinder.ForEachLine(r, func(timestamp int64, line string, fields timefinder.Fields) {
+		s.order.check(timestamp)
+		s.count(s.binner, s.series, timestamp, line, fields)
+	})
+	s.stats.Add(stats)
+	if err != nil {
+		return fmt.Errorf("failed to read log: %v", err)
+	}
+	return nil
+}
+
+// scanChunks scans each chunk in its own goroutine with its own TimeFinder, binners, and order checker, then merges
+// the results. Lines that are out of order with respect to the end of the previous chunk aren't counted.
+func (s *scanner) scanChunks(chunks []*io.SectionReader) error {
+	binners := make([]*streamingBinner, len(chunks))
+	series := make([]*seriesBinners, len(chunks))
+	orders := make([]*orderChecker, len(chunks))
+	stats := make([]timefinder.ScanStats, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		binners[i] = s.binner.empty()
+		orders[i] = newOrderChecker(s.order.tolerance)
+		if s.splitter != nil {
+			series[i] = newSeriesBinners(s.binner)
+		}
+		timeFinder := s.timeFinder.Copy()
+
+		var r io.Reader = chunk
+		if s.tracker != nil {
+			r = NewProgressReader(r, s.tracker)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats[i], errs[i] = timeFinder.ForEachLine(r, func(timestamp int64, line string, fields timefinder.Fields) {
+				orders[i].check(timestamp)
+				s.count(binners[i], series[i], timestamp, line, fields)
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i := range chunks {
+		if errs[i] != nil {
+			return fmt.Errorf("failed to read log: %v", errs[i])
+		}
+		s.binner.merge(binners[i])
+		if s.splitter != nil {
+			s.series.merge(series[i])
+		}
+		s.order.outOfOrder += orders[i].outOfOrder
+		s.stats.Add(stats[i])
+	}
+	return nil
+}
+
+// splitIntoChunks divides the first size bytes of r into at most n chunks of similar size, each of which starts at the
+// beginning of a line. Chunks are never smaller than minChunkSize unless the input is.
+func splitIntoChunks(r io.ReaderAt, size int64, n int) ([]*io.SectionReader, error) {
+	if maxChunks := int(size / minChunkSize); n > maxChunks {
+		n = maxChunks
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	chunks := make([]*io.SectionReader, 0, n)
+	start := int64(0)
+	for i := 1; i <= n && start < size; i++ {
+		end, err := nextLineStart(r, size, size*int64(i)/int64(n))
+		if err != nil {
+			return nil, err
+		}
+		if end > start {
+			chunks = append(chunks, io.NewSectionReader(r, start, end-start))
+		}
+		start = end
+	}
+	return chunks, nil
+}
+
+// nextLineStart returns the offset of the first line that starts at or after offset, or size if there isn't one.
+func nextLineStart(r io.ReaderAt, size int64, offset int64) (int64, error) {
+	if offset <= 0 || offset >= size {
+		return min(max(offset, 0), size), nil
+	}
+
+	buf := make([]byte, 4096)
+	for position := offset - 1; position < size; position += int64(len(buf)) {
+		n, err := r.ReadAt(buf, position)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return position + int64(i) + 1, nil
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+// count adds the timestamp of a line, along with its value if values are being aggregated, to the binner and to the
+// binner of the line's series if the lines are split. The line's fields are nil unless the lines are structured.
+func (s *scanner) count(binner *streamingBinner, series *seriesBinners, timestamp int64, line string, fields timefinder.Fields) {
+	value := math.NaN()
+	if s.values != nil {
+		if v, ok := s.values.value(line, fields); ok {
+			value = v
+		}
+	}
+	binner.addWithValue(timestamp, value)
+	if s.splitter != nil {
+		key, ok := s.splitter.key(line, fields)
+		series.add(key, ok, timestamp, value)
+	}
+}
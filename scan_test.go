@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/acj/krapslog/timefinder"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSampleLog writes a log of at least size bytes, with one line every 10ms starting at 06:26:40 on Nov 23, 2019.
+func writeSampleLog(tb testing.TB, size int) string {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	for i := 0; buf.Len() < size; i++ {
+		timestamp := first.Add(time.Duration(i) * 10 * time.Millisecond).Format(apacheCommonLogFormatDate)
+		fmt.Fprintf(&buf, "10.1.1.10:57305 [%s] public myapp/i-05fa49c0e7db8c328 0/0/0/78/78 206 913/458 - - ---- 9/9/6/0/0 0/0 \"GET /2518cb13a48bdf53b2f936f44e7042a3cc7baa06 HTTP/1.1\"\n", timestamp)
+	}
+
+	filename := filepath.Join(tb.TempDir(), "haproxy.log")
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		tb.Fatalf("failed to write log: %v", err)
+	}
+	return filename
+}
+
+func Test_nextLineStart(t *testing.T) {
+	input := "one\ntwo\nthree"
+	tests := []struct {
+		name   string
+		offset int64
+		want   int64
+	}{
+		{"start of input", 0, 0},
+		{"start of a line", 4, 4},
+		{"middle of a line", 5, 8},
+		{"end of a line", 7, 8},
+		{"last line", 9, int64(len(input))},
+		{"end of input", int64(len(input)), int64(len(input))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextLineStart(strings.NewReader(input), int64(len(input)), tt.offset)
+			if err != nil {
+				t.Fatalf("nextLineStart() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("nextLineStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitIntoChunks(t *testing.T) {
+	line := strings.Repeat("x", 999) + "\n"
+	tests := []struct {
+		name       string
+		size       int
+		n          int
+		wantChunks int
+	}{
+		{"small input", 10 * len(line), 4, 1},
+		{"large input", 4 * minChunkSize, 4, 4},
+		{"limited by the minimum chunk size", 2 * minChunkSize, 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := []byte(strings.Repeat(line, (tt.size+len(line)-1)/len(line)))
+			chunks, err := splitIntoChunks(bytes.NewReader(input), int64(len(input)), tt.n)
+			if err != nil {
+				t.Fatalf("splitIntoChunks() error = %v", err)
+			}
+			if len(chunks) != tt.wantChunks {
+				t.Errorf("len(chunks) = %v, want %v", len(chunks), tt.wantChunks)
+			}
+
+			var joined bytes.Buffer
+			for _, chunk := range chunks {
+				data, err := io.ReadAll(chunk)
+				if err != nil {
+					t.Fatalf("failed to read chunk: %v", err)
+				}
+				if !bytes.HasSuffix(data, []byte("\n")) {
+					t.Errorf("chunk doesn't end at the end of a line")
+				}
+				joined.Write(data)
+			}
+			if !bytes.Equal(joined.Bytes(), input) {
+				t.Errorf("chunks don't cover the input exactly")
+			}
+		})
+	}
+}
+
+func Test_scannerInParallel(t *testing.T) {
+	filename := writeSampleLog(t, 3*minChunkSize+1000)
+
+	scan := func(parallelism int) *streamingBinner {
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatalf("failed to open log: %v", err)
+		}
+		defer file.Close()
+
+		timeFinder, err := timefinder.NewTimeFinder(apacheCommonLogFormatDate)
+		if err != nil {
+			t.Fatalf("NewTimeFinder() error = %v", err)
+		}
+		s := scanner{
+			timeFinder:  timeFinder,
+			binner:      newStreamingBinner(80),
+			order:       newOrderChecker(0),
+			parallelism: parallelism,
+		}
+		if err := s.scan(file); err != nil {
+			t.Fatalf("scan() error = %v", err)
+		}
+		if s.order.outOfOrder != 0 {
+			t.Errorf("outOfOrder = %v, want 0", s.order.outOfOrder)
+		}
+		return s.binner
+	}
+
+	sequential, parallel := scan(1), scan(4)
+	if parallel.count != sequential.count {
+		t.Errorf("count = %v, want %v", parallel.count, sequential.count)
+	}
+	if got, want := parallel.bins(parallel.bounds()), sequential.bins(sequential.bounds()); !reflect.DeepEqual(got, want) {
+		t.Errorf("bins() = %v, want %v", got, want)
+	}
+}
+
+func Benchmark_scanner(b *testing.B) {
+	filename := writeSampleLog(b, 64<<20)
+	stat, err := os.Stat(filename)
+	if err != nil {
+		b.Fatalf("failed to stat log: %v", err)
+	}
+
+	parallelisms := []int{1, 2, 4}
+	if cpus := runtime.NumCPU(); cpus > 4 {
+		parallelisms = append(parallelisms, cpus)
+	}
+	for _, parallelism := range parallelisms {
+		b.Run(fmt.Sprintf("parallelism %d", parallelism), func(b *testing.B) {
+			b.SetBytes(stat.Size())
+			for i := 0; i < b.N; i++ {
+				file, err := os.Open(filename)
+				if err != nil {
+					b.Fatalf("failed to open log: %v", err)
+				}
+				timeFinder, err := timefinder.NewTimeFinder(apacheCommonLogFormatDate)
+				if err != nil {
+					b.Fatalf("NewTimeFinder() error = %v", err)
+				}
+				s := scanner{
+					timeFinder:  timeFinder,
+					binner:      newStreamingBinner(80),
+					order:       newOrderChecker(0),
+					parallelism: parallelism,
+				}
+				if err := s.scan(file); err != nil {
+					b.Fatalf("scan() error = %v", err)
+				}
+				file.Close()
+			}
+		})
+	}
+}
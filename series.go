@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"github.com/acj/krapslog/timefinder"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// otherSeriesLabel labels the series that holds the lines that don't belong to any of the series that are shown.
+	otherSeriesLabel = "other"
+	// maxTrackedSeries is the number of distinct series that are counted separately. Lines that belong to any further
+	// series are counted as other, which keeps the memory use bounded when the split pattern captures something like
+	// an IP address.
+	maxTrackedSeries = 1000
+	// maxSeriesLabelWidth is the width that series labels are truncated to.
+	maxSeriesLabelWidth = 16
+)
+
+// seriesSplitter assigns lines to series using the first capture group of a regular expression, or the whole match if
+// there isn't a capture group. For structured lines, it can use the value of a field instead.
+type seriesSplitter struct {
+	re *regexp.Regexp
+	// field is the path of the field whose value is the series, or empty to use re
+	field string
+}
+
+func newSeriesSplitter(pattern string) (*seriesSplitter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &seriesSplitter{re: re}, nil
+}
+
+func newFieldSeriesSplitter(field string) *seriesSplitter {
+	return &seriesSplitter{field: field}
+}
+
+// key returns the series that the line belongs to. The second return value is false if the line doesn't match, or if
+// it doesn't have the field.
+func (s *seriesSplitter) key(line string, fields timefinder.Fields) (string, bool) {
+	if s.field != "" {
+		return fields.Field(s.field)
+	}
+	match := s.re.FindStringSubmatch(line)
+	if match == nil {
+		return "", false
+	}
+	if len(match) > 1 {
+		return match[1], true
+	}
+	return match[0], true
+}
+
+// seriesBinners counts the timestamps of each series separately.
+type seriesBinners struct {
+	binners map[string]*streamingBinner
+	// other counts the lines that don't belong to a series, or that belong to a series beyond maxTrackedSeries
+	other *streamingBinner
+}
+
+// newSeriesBinners creates binners for the series that count, and aggregate values, like template does.
+func newSeriesBinners(template *streamingBinner) *seriesBinners {
+	return &seriesBinners{
+		binners: make(map[string]*streamingBinner),
+		other:   template.empty(),
+	}
+}
+
+// add adds the timestamp of a line that has the given value, or NaN if it doesn't have one.
+func (s *seriesBinners) add(key string, ok bool, lineUnixNanos int64, value float64) {
+	s.binnerFor(key, ok).addWithValue(lineUnixNanos, value)
+}
+
+func (s *seriesBinners) binnerFor(key string, ok bool) *streamingBinner {
+	if !ok {
+		return s.other
+	}
+	b, found := s.binners[key]
+	if !found {
+		if len(s.binners) >= maxTrackedSeries {
+			return s.other
+		}
+		b = s.other.empty()
+		s.binners[key] = b
+	}
+	return b
+}
+
+// merge adds the timestamps that were counted by other, which must have been created from a similar template.
+func (s *seriesBinners) merge(other *seriesBinners) {
+	for key, b := range other.binners {
+		s.binnerFor(key, true).merge(b)
+	}
+	s.other.merge(other.other)
+}
+
+// series is a labeled set of timestamps.
+type series struct {
+	label  string
+	binner *streamingBinner
+}
+
+// top returns the n series with the most lines, in descending order. The lines of the remaining series, along with the
+// lines that didn't belong to a series, are combined into a final series labeled "other" if there are any.
+func (s *seriesBinners) top(n int) []series {
+	all := make([]series, 0, len(s.binners))
+	for label, b := range s.binners {
+		all = append(all, series{label: label, binner: b})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].binner.count != all[j].binner.count {
+			return all[i].binner.count > all[j].binner.count
+		}
+		return all[i].label < all[j].label
+	})
+
+	if n < 0 || n > len(all) {
+		n = len(all)
+	}
+	top := append([]series{}, all[:n]...)
+
+	other := s.other.empty()
+	other.merge(s.other)
+	for _, rest := range all[n:] {
+		other.merge(rest.binner)
+	}
+	if other.count > 0 {
+		top = append(top, series{label: otherSeriesLabel, binner: other})
+	}
+	return top
+}
+
+// seriesLabelWidth returns the number of columns needed for the labels of the series, including a space to separate
+// them from the sparklines.
+func seriesLabelWidth(series []series) int {
+	width := 0
+	for _, s := range series {
+		width = max(width, utf8.RuneCountInString(seriesLabel(s.label)))
+	}
+	return width + 1
+}
+
+func seriesLabel(label string) string {
+	if runes := []rune(label); len(runes) > maxSeriesLabelWidth {
+		return string(runes[:maxSeriesLabelWidth-1]) + "…"
+	}
+	return label
+}
+
+// renderSeries draws the sparklines of the series, which share the range from firstTime to lastTime. The labels take
+// up part of the terminal's width, so the sparklines are narrower than the single sparkline that's normally drawn.
+func renderSeries(rows []series, firstTimestamp, lastTimestamp time.Time, firstTime, lastTime int64, timeMarkerCount int, terminalWidth int, look sparklineLook) string {
+	labelWidth := seriesLabelWidth(rows)
+	sparklineWidth := max(terminalWidth-labelWidth, 1)
+	labels := make([]string, len(rows))
+	counts := make([][]float64, len(rows))
+	for i, row := range rows {
+		labels[i] = row.label
+		counts[i] = row.binner.levels(firstTime, lastTime, sparklineWidth*look.style.bucketsPerColumn())
+	}
+	return renderStackedSparklines(labels, counts, firstTimestamp, lastTimestamp, timeMarkerCount, labelWidth, sparklineWidth, look)
+}
+
+// renderStackedSparklines draws a labeled sparkline for each series, one above the other. The sparklines share the
+// time markers, which are indented past the labels so that they line up with the sparklines' columns. A sparkline
+// that's more than one row tall has its label on the top row.
+func renderStackedSparklines(labels []string, counts [][]float64, firstTimestamp, lastTimestamp time.Time, timeMarkerCount int, labelWidth, sparklineWidth int, look sparklineLook) string {
+	header, footer := renderHeaderAndFooter(firstTimestamp, lastTimestamp, timeMarkerCount, sparklineWidth)
+	indent := strings.Repeat(" ", labelWidth)
+
+	var b strings.Builder
+	b.WriteString(indentLines(header, indent))
+	for i, label := range labels {
+		for row, sparkLine := range look.rows(counts[i], i) {
+			if row > 0 {
+				label = ""
+			}
+			fmt.Fprintf(&b, "%s%s\n", look.colors.label(fmt.Sprintf("%-*s", labelWidth, seriesLabel(label)), i), sparkLine)
+		}
+	}
+	b.WriteString(indentLines(footer, indent))
+	return b.String()
+}
+
+// indentLines adds the indent to the start of each line of s.
+func indentLines(s string, indent string) string {
+	if s == "" {
+		return ""
+	}
+	return indent + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n"+indent) + "\n"
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_seriesSplitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		line    string
+		wantKey string
+		wantOk  bool
+	}{
+		{"capture group", `status=(\d+)`, "GET / status=404 took 3ms", "404", true},
+		{"whole match", `GET|POST`, "POST /login status=200", "POST", true},
+		{"first of several groups", `(\w+)=(\d+)`, "status=500", "status", true},
+		{"no match", `status=(\d+)`, "GET / took 3ms", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splitter, err := newSeriesSplitter(tt.pattern)
+			if err != nil {
+				t.Fatalf("newSeriesSplitter() error = %v", err)
+			}
+			key, ok := splitter.key(tt.line, nil)
+			if key != tt.wantKey || ok != tt.wantOk {
+				t.Errorf("key() = %q, %v, want %q, %v", key, ok, tt.wantKey, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_seriesBinnersTop(t *testing.T) {
+	type row struct {
+		label string
+		count int
+	}
+	keys := []struct {
+		key string
+		ok  bool
+	}{
+		{"200", true}, {"200", true}, {"200", true}, {"404", true}, {"404", true}, {"500", true}, {"", false},
+	}
+	tests := []struct {
+		name string
+		n    int
+		want []row
+	}{
+		{"all series", 5, []row{{"200", 3}, {"404", 2}, {"500", 1}, {otherSeriesLabel, 1}}},
+		{"remaining series are other", 1, []row{{"200", 3}, {otherSeriesLabel, 4}}},
+		{"no series", 0, []row{{otherSeriesLabel, 7}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSeriesBinners(newStreamingBinner(10))
+			for i, k := range keys {
+				s.add(k.key, k.ok, int64(i), math.NaN())
+			}
+
+			var got []row
+			for _, series := range s.top(tt.n) {
+				got = append(got, row{series.label, series.binner.count})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("top() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_seriesBinnersLimit(t *testing.T) {
+	s := newSeriesBinners(newStreamingBinner(10))
+	for i := 0; i < maxTrackedSeries+10; i++ {
+		s.add(strings.Repeat("x", i+1), true, int64(i), math.NaN())
+	}
+	if len(s.binners) != maxTrackedSeries {
+		t.Errorf("len(binners) = %v, want %v", len(s.binners), maxTrackedSeries)
+	}
+	if s.other.count != 10 {
+		t.Errorf("other.count = %v, want 10", s.other.count)
+	}
+}
+
+func Test_seriesLabel(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"200", "200"},
+		{"exactly-sixteen!", "exactly-sixteen!"},
+		{"a-label-that-is-too-long", "a-label-that-is…"},
+		{"ünïcödé-ünïcödé-ünïcödé", "ünïcödé-ünïcödé…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got := seriesLabel(tt.label); got != tt.want {
+				t.Errorf("seriesLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_indentLines(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"empty", "", ""},
+		{"one line", "a\n", "  a\n"},
+		{"several lines", "a\nb\n", "  a\n  b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indentLines(tt.s, "  "); got != tt.want {
+				t.Errorf("indentLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderStackedSparklines(t *testing.T) {
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	counts := [][]float64{make([]float64, 20), make([]float64, 20)}
+	counts[0][0], counts[0][19] = 1, 2
+	counts[1][10] = 1
+	got := renderStackedSparklines([]string{"GET", "POST"}, counts, first, first.Add(19*time.Second), 2, 5, 20, sparklineLook{})
+
+	want := `      Sat Nov 23 06:26:58
+                        |
+GET  ▅▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁▁█
+POST ▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▁
+     |                   
+     Sat Nov 23 06:26:40 
+`
+	if got != want {
+		t.Errorf("renderStackedSparklines() = %q, want %q", got, want)
+	}
+}
+
+func Test_renderStackedSparklinesWithHeight(t *testing.T) {
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	counts := [][]float64{{0, 1, 2, 3}, {3, 0, 0, 0}}
+	got := renderStackedSparklines([]string{"GET", "POST"}, counts, first, first.Add(3*time.Second), 0, 5, 4, sparklineLook{height: 2})
+
+	// The labels go on the top row of each sparkline
+	want := "GET    ▃█\n     ▁▆██\nPOST █   \n     █▁▁▁\n"
+	if got != want {
+		t.Errorf("renderStackedSparklines() = %q, want %q", got, want)
+	}
+}
+
+func Test_renderStackedSparklinesWithSeriesColors(t *testing.T) {
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	counts := [][]float64{{0, 1}, {1, 0}}
+	look := sparklineLook{colors: &colorScheme{gradient: gradient{{0, 0, 0}}, bySeries: true}}
+	got := renderStackedSparklines([]string{"GET", "POST"}, counts, first, first.Add(time.Second), 0, 5, 2, look)
+
+	// Each label is drawn in the same color as its sparkline
+	blue, orange := seriesColors[0].foreground(), seriesColors[1].foreground()
+	want := blue + "GET  " + colorReset + blue + "▁█" + colorReset + "\n" + orange + "POST " + colorReset + orange + "█▁" + colorReset + "\n"
+	if got != want {
+		t.Errorf("renderStackedSparklines() = %q, want %q", got, want)
+	}
+}
@@ -9,21 +9,74 @@ import (
 
 var steps = []rune("▁▂▃▄▅▆▇█")
 
-// Line generates a sparkline string from a slice of
-// float64s.
-func Line(nums []float64) string {
+// Cell is one character of a sparkline. Value is how
+// high the number that it shows is relative to the others,
+// from 0 for the lowest to 1 for the highest, so that the
+// cell can be colored by it.
+type Cell struct {
+	Rune  rune
+	Value float64
+}
+
+// Text returns the characters of the cells without color.
+func Text(cells []Cell) string {
+	var text bytes.Buffer
+	for _, cell := range cells {
+		text.WriteRune(cell.Rune)
+	}
+	return text.String()
+}
+
+// Line generates a sparkline from a slice of float64s.
+func Line(nums []float64) []Cell {
+	if len(nums) == 0 {
+		return nil
+	}
+	return Lines(nums, 1)[0]
+}
+
+// Lines generates a sparkline that's height rows tall, so
+// that it has eight levels per row. The rows are returned
+// from top to bottom, and the bottom row is the same as the
+// one that Line would draw if height were 1.
+func Lines(nums []float64, height int) [][]Cell {
+	if height < 1 {
+		height = 1
+	}
+	rows := make([][]Cell, height)
 	if len(nums) == 0 {
-		return ""
+		return rows
 	}
-	indices := normalize(nums)
-	var sparkline bytes.Buffer
+	levels := len(steps) * height
+	indices := normalize(nums, levels)
 	for _, index := range indices {
-		sparkline.WriteRune(steps[index])
+		value := relativeValue(index, levels)
+		for row := range rows {
+			// Each row above the bottom one fills up once the rows below it are full
+			level := index - (height-1-row)*len(steps)
+			switch {
+			case level < 0:
+				rows[row] = append(rows[row], Cell{' ', value})
+			case level >= len(steps):
+				rows[row] = append(rows[row], Cell{steps[len(steps)-1], value})
+			default:
+				rows[row] = append(rows[row], Cell{steps[level], value})
+			}
+		}
+	}
+	return rows
+}
+
+// relativeValue maps an index from normalize to a value
+// from 0 to 1.
+func relativeValue(index, levels int) float64 {
+	if levels < 2 {
+		return 0
 	}
-	return sparkline.String()
+	return float64(index) / float64(levels-1)
 }
 
-func normalize(nums []float64) []int {
+func normalize(nums []float64, levels int) []int {
 	var indices []int
 	min := minimum(nums)
 	for i, _ := range nums {
@@ -38,9 +91,9 @@ func normalize(nums []float64) []int {
 	for i, _ := range nums {
 		x := nums[i]
 		x /= max
-		x *= 8
-		if x == 8 {
-			x = 7
+		x *= float64(levels)
+		if x == float64(levels) {
+			x = float64(levels - 1)
 		} else {
 			x = math.Floor(x)
 		}
@@ -68,3 +121,51 @@ func maximum(nums []float64) float64 {
 	}
 	return max
 }
+
+// brailleDots are the bits of the dots in a braille cell,
+// from the bottom of each column to the top.
+var brailleDots = [2][4]rune{
+	{0x40, 0x04, 0x02, 0x01},
+	{0x80, 0x20, 0x10, 0x08},
+}
+
+// Braille generates a sparkline that's height rows tall
+// using braille cells, which have two columns of four dots.
+// Each cell shows two of the numbers, so the sparkline is
+// half as wide as the one that Lines would draw. The rows
+// are returned from top to bottom.
+func Braille(nums []float64, height int) [][]Cell {
+	if height < 1 {
+		height = 1
+	}
+	rows := make([][]Cell, height)
+	if len(nums) == 0 {
+		return rows
+	}
+	dotsPerColumn := len(brailleDots[0])
+	levels := dotsPerColumn * height
+	indices := normalize(nums, levels)
+	for cell := 0; cell < len(indices); cell += 2 {
+		// The cell is as high as the higher of its numbers
+		value := relativeValue(indices[cell], levels)
+		if cell+1 < len(indices) {
+			value = math.Max(value, relativeValue(indices[cell+1], levels))
+		}
+		for row := range rows {
+			var pattern rune
+			for column := 0; column < 2 && cell+column < len(indices); column++ {
+				// Like Lines, the lowest number still gets a dot
+				dots := indices[cell+column] + 1 - (height-1-row)*dotsPerColumn
+				for dot := 0; dot < dots && dot < dotsPerColumn; dot++ {
+					pattern |= brailleDots[column][dot]
+				}
+			}
+			if pattern == 0 {
+				rows[row] = append(rows[row], Cell{' ', value})
+			} else {
+				rows[row] = append(rows[row], Cell{0x2800 + pattern, value})
+			}
+		}
+	}
+	return rows
+}
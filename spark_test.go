@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		nums   []float64
+		height int
+		want   []string
+	}{
+		{"one row is the same as Line", []float64{0, 1, 2, 3, 4, 5, 6, 7}, 1, []string{"▁▂▃▄▅▆▇█"}},
+		{"two rows", []float64{0, 1, 2, 3}, 2, []string{"  ▃█", "▁▆██"}},
+		{"more rows show smaller changes", []float64{0, 10, 100}, 3, []string{"  █", "  █", "▁▃█"}},
+		{"all the same", []float64{5, 5}, 2, []string{"  ", "▁▁"}},
+		{"no values", nil, 2, []string{"", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := texts(Lines(tt.nums, tt.height)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBraille(t *testing.T) {
+	tests := []struct {
+		name   string
+		nums   []float64
+		height int
+		want   []string
+	}{
+		{"two numbers per cell", []float64{0, 1, 2, 3}, 1, []string{"⣠⣾"}},
+		{"two rows", []float64{0, 7}, 2, []string{"⢸", "⣸"}},
+		{"an odd count leaves the last right column empty", []float64{0, 1, 2}, 1, []string{"⣰⡇"}},
+		{"empty cells are spaces", []float64{0, 0}, 2, []string{" ", "⣀"}},
+		{"no values", nil, 1, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := texts(Braille(tt.nums, tt.height)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Braille() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLine_values(t *testing.T) {
+	cells := Line([]float64{2, 4, 6})
+	want := []Cell{{'▁', 0}, {'▅', 4.0 / 7}, {'█', 1}}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("Line() = %v, want %v", cells, want)
+	}
+}
+
+func TestBraille_values(t *testing.T) {
+	// Each cell is as high as the higher of its two numbers
+	rows := Braille([]float64{0, 3, 0, 0}, 1)
+	if got := []float64{rows[0][0].Value, rows[0][1].Value}; !reflect.DeepEqual(got, []float64{1, 0}) {
+		t.Errorf("Braille() values = %v, want %v", got, []float64{1, 0})
+	}
+}
+
+// texts returns the text of each row of cells.
+func texts(rows [][]Cell) []string {
+	lines := make([]string, len(rows))
+	for i, cells := range rows {
+		lines[i] = Text(cells)
+	}
+	return lines
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/acj/krapslog/timefinder"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	statsFormatNone = ""
+	statsFormatText = "text"
+	statsFormatJSON = "json"
+)
+
+// statsFormatFlag is the value of the -stats flag. It can be given without a value to get the text format, so like a
+// bool flag, any other value must follow an equals sign (-stats=json).
+type statsFormatFlag string
+
+func (f *statsFormatFlag) String() string {
+	return string(*f)
+}
+
+func (f *statsFormatFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*f = statsFormatText
+	case "false":
+		*f = statsFormatNone
+	case statsFormatText, statsFormatJSON:
+		*f = statsFormatFlag(value)
+	default:
+		return fmt.Errorf("must be '%s' or '%s'", statsFormatText, statsFormatJSON)
+	}
+	return nil
+}
+
+func (f *statsFormatFlag) IsBoolFlag() bool {
+	return true
+}
+
+// scanReport summarizes a scan of the logs for the -stats flag. Rates are in lines per second.
+type scanReport struct {
+	TotalLines       int      `json:"total_lines"`
+	MatchedLines     int      `json:"matched_lines"`
+	FilteredLines    int      `json:"filtered_lines"`
+	UnmatchedLines   int      `json:"unmatched_lines"`
+	UnmatchedSamples []string `json:"unmatched_samples"`
+	OutOfRangeLines  int      `json:"out_of_range_lines"`
+	LongLines        int      `json:"long_lines"`
+	OutOfOrderLines  int      `json:"out_of_order_lines"`
+	// SkippedBytes is the size of the parts of the logs that weren't scanned because a search showed that they're
+	// outside of the time range. The lines in them aren't counted in the other fields.
+	SkippedBytes int64 `json:"skipped_bytes"`
+	// FirstTimestamp and LastTimestamp are nil if no lines matched
+	FirstTimestamp *time.Time `json:"first_timestamp"`
+	LastTimestamp  *time.Time `json:"last_timestamp"`
+	SpanSeconds    float64    `json:"span_seconds"`
+	PeakRate       float64    `json:"peak_rate"`
+	AverageRate    float64    `json:"average_rate"`
+}
+
+// newScanReport builds the report from the stats of a scan. If any lines matched, their times are added with
+// addTimestamps.
+func newScanReport(stats timefinder.ScanStats, outOfOrder int) scanReport {
+	report := scanReport{
+		TotalLines:       stats.Lines,
+		MatchedLines:     stats.Matched,
+		FilteredLines:    stats.Filtered,
+		UnmatchedLines:   stats.Unmatched,
+		UnmatchedSamples: stats.UnmatchedSamples,
+		OutOfRangeLines:  stats.OutOfRange,
+		LongLines:        stats.LongLines,
+		OutOfOrderLines:  outOfOrder,
+	}
+	if report.UnmatchedSamples == nil {
+		report.UnmatchedSamples = []string{}
+	}
+	return report
+}
+
+// addTimestamps adds the times of the matching lines to the report. The lines were counted into buckets that span
+// the range from firstTime to lastTime, which may be wider than the range from the first timestamp to the last.
+func (r *scanReport) addTimestamps(firstTimestamp, lastTimestamp time.Time, firstTime, lastTime int64, buckets []float64) {
+	r.FirstTimestamp, r.LastTimestamp = &firstTimestamp, &lastTimestamp
+	r.SpanSeconds = lastTimestamp.Sub(firstTimestamp).Seconds()
+
+	span := time.Duration(lastTime - firstTime + 1)
+	if span > time.Nanosecond && len(buckets) > 0 {
+		r.AverageRate = float64(r.MatchedLines) / span.Seconds()
+		bucketSeconds := span.Seconds() / float64(len(buckets))
+		for _, count := range buckets {
+			if rate := count / bucketSeconds; rate > r.PeakRate {
+				r.PeakRate = rate
+			}
+		}
+	}
+}
+
+func (r scanReport) write(w io.Writer, format string) error {
+	if format == statsFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "total lines:         %d\n", r.TotalLines)
+	fmt.Fprintf(&b, "matched lines:       %d\n", r.MatchedLines)
+	fmt.Fprintf(&b, "filtered lines:      %d\n", r.FilteredLines)
+	fmt.Fprintf(&b, "unmatched lines:     %d\n", r.UnmatchedLines)
+	fmt.Fprintf(&b, "out of range lines:  %d\n", r.OutOfRangeLines)
+	fmt.Fprintf(&b, "long lines:          %d\n", r.LongLines)
+	fmt.Fprintf(&b, "out of order lines:  %d\n", r.OutOfOrderLines)
+	if r.SkippedBytes > 0 {
+		fmt.Fprintf(&b, "skipped bytes:       %d (outside the time range, so their lines weren't scanned or counted)\n", r.SkippedBytes)
+	}
+	if r.FirstTimestamp != nil && r.LastTimestamp != nil {
+		fmt.Fprintf(&b, "first timestamp:     %s\n", r.FirstTimestamp.Format(time.RFC3339Nano))
+		fmt.Fprintf(&b, "last timestamp:      %s\n", r.LastTimestamp.Format(time.RFC3339Nano))
+		fmt.Fprintf(&b, "span:                %v\n", r.LastTimestamp.Sub(*r.FirstTimestamp))
+		fmt.Fprintf(&b, "peak rate:           %.2f lines/s\n", r.PeakRate)
+		fmt.Fprintf(&b, "average rate:        %.2f lines/s\n", r.AverageRate)
+	}
+	if len(r.UnmatchedSamples) > 0 {
+		fmt.Fprintf(&b, "unmatched samples:\n")
+		for _, line := range r.UnmatchedSamples {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/acj/krapslog/timefinder"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_statsFormatFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    statsFormatFlag
+		wantErr bool
+	}{
+		{"true", statsFormatText, false},
+		{"false", statsFormatNone, false},
+		{"text", statsFormatText, false},
+		{"json", statsFormatJSON, false},
+		{"xml", statsFormatNone, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var f statsFormatFlag
+			err := f.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if f != tt.want {
+				t.Errorf("Set() = %q, want %q", f, tt.want)
+			}
+		})
+	}
+}
+
+func Test_scanReport(t *testing.T) {
+	stats := timefinder.ScanStats{Lines: 8, Matched: 4, Filtered: 1, Unmatched: 2, UnmatchedSamples: []string{"hi mom"}, LongLines: 1}
+	report := newScanReport(stats, 1)
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	// Four lines over two seconds, with three of them in the first second
+	report.addTimestamps(first, first.Add(1500*time.Millisecond), first.UnixNano(), first.Add(2*time.Second).UnixNano()-1, []float64{3, 1})
+
+	output := &bytes.Buffer{}
+	if err := report.write(output, statsFormatText); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	expected := `total lines:         8
+matched lines:       4
+filtered lines:      1
+unmatched lines:     2
+out of range lines:  0
+long lines:          1
+out of order lines:  1
+first timestamp:     2019-11-23T06:26:40Z
+last timestamp:      2019-11-23T06:26:41.5Z
+span:                1.5s
+peak rate:           3.00 lines/s
+average rate:        2.00 lines/s
+unmatched samples:
+  hi mom
+`
+	if output.String() != expected {
+		t.Errorf("incorrect output\n\nwanted:\n%s\ngot:\n%s", expected, output.String())
+	}
+
+	// When parts of the logs outside of the time range were skipped, the report says so
+	report.SkippedBytes = 1024
+	output.Reset()
+	if err := report.write(output, statsFormatText); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	if want := "out of order lines:  1\nskipped bytes:       1024 "; !strings.Contains(output.String(), want) {
+		t.Errorf("write() = %q, want it to contain %q", output.String(), want)
+	}
+}
+
+func Test_displaySparklineWithStats(t *testing.T) {
+	output := &bytes.Buffer{}
+	logLines := "this line doesn't have a timestamp\n" + sampleLogLines
+	err := displaySparkline([]io.Reader{strings.NewReader(logLines)}, output, options{dateFormat: apacheCommonLogFormatDate, statsFormat: statsFormatJSON})
+	if err != nil {
+		t.Fatalf("displaySparkline() error = %v", err)
+	}
+
+	// The stats follow the sparkline
+	_, reportJSON, _ := strings.Cut(output.String(), "\n")
+	var report scanReport
+	if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
+		t.Fatalf("couldn't parse report: %v\n%s", err, reportJSON)
+	}
+	if report.TotalLines != 11 || report.MatchedLines != 10 || report.UnmatchedLines != 1 {
+		t.Errorf("report counts = %d/%d/%d, want 11/10/1", report.TotalLines, report.MatchedLines, report.UnmatchedLines)
+	}
+	if len(report.UnmatchedSamples) != 1 || report.UnmatchedSamples[0] != "this line doesn't have a timestamp" {
+		t.Errorf("report.UnmatchedSamples = %q", report.UnmatchedSamples)
+	}
+	if want := time.Date(2019, time.November, 23, 6, 26, 40, 781000000, time.UTC); report.FirstTimestamp == nil || !report.FirstTimestamp.Equal(want) {
+		t.Errorf("report.FirstTimestamp = %v, want %v", report.FirstTimestamp, want)
+	}
+	if report.SpanSeconds != 9.098 {
+		t.Errorf("report.SpanSeconds = %v, want 9.098", report.SpanSeconds)
+	}
+}
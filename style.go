@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	styleBlocks  = "blocks"
+	styleBraille = "braille"
+)
+
+// sparklineStyle is how the sparkline is drawn. It's the value of the -style flag. The zero value is styleBlocks.
+type sparklineStyle string
+
+func (s *sparklineStyle) String() string {
+	return string(*s)
+}
+
+func (s *sparklineStyle) Set(value string) error {
+	switch value {
+	case styleBlocks, styleBraille:
+		*s = sparklineStyle(value)
+	default:
+		return fmt.Errorf("must be '%s' or '%s'", styleBlocks, styleBraille)
+	}
+	return nil
+}
+
+// bucketsPerColumn is the number of buckets that each column of the sparkline shows.
+func (s sparklineStyle) bucketsPerColumn() int {
+	if s == styleBraille {
+		return 2
+	}
+	return 1
+}
+
+// lines draws the sparkline for the given bucket counts, which should be a multiple of bucketsPerColumn. The rows are
+// returned from top to bottom.
+func (s sparklineStyle) lines(counts []float64, height int) [][]Cell {
+	if s == styleBraille {
+		return Braille(counts, height)
+	}
+	return Lines(counts, height)
+}
+
+// sparklineLook is everything about how sparklines are drawn other than their width.
+type sparklineLook struct {
+	style  sparklineStyle
+	height int
+	colors *colorScheme
+}
+
+// rows draws the sparkline for the given bucket counts, one string per row from top to bottom. The series is the index
+// of the sparkline among the series, or -1 if it isn't one of them.
+func (l sparklineLook) rows(counts []float64, series int) []string {
+	lines := l.style.lines(counts, l.height)
+	rows := make([]string, len(lines))
+	for i, cells := range lines {
+		rows[i] = l.colors.paint(cells, series)
+	}
+	return rows
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_sparklineStyle_Set(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    sparklineStyle
+		wantErr bool
+	}{
+		{"blocks", styleBlocks, false},
+		{"braille", styleBraille, false},
+		{"dots", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var s sparklineStyle
+			err := s.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if s != tt.want {
+				t.Errorf("Set() = %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sparklineStyle_lines(t *testing.T) {
+	counts := []float64{0, 1, 2, 3, 4, 5, 6, 7}
+	tests := []struct {
+		style sparklineStyle
+		want  string
+	}{
+		{"", "▁▂▃▄▅▆▇█"},
+		{styleBlocks, "▁▂▃▄▅▆▇█"},
+		{styleBraille, "⣀⣤⣶⣿"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			// lines modifies the counts
+			got := texts(tt.style.lines(append([]float64(nil), counts...), 1))
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("lines() = %q, want [%q]", got, tt.want)
+			}
+			if width, want := len([]rune(got[0]))*tt.style.bucketsPerColumn(), len(counts); width != want {
+				t.Errorf("lines() drew %d buckets, want %d", width, want)
+			}
+		})
+	}
+}
@@ -71,16 +71,31 @@ func (c canvas) String() string {
 type timeMarker struct {
 	horizontalOffset int
 	time             time.Time
+	// layout is the format of the marker's label. If it's empty, goAnsicTimeFormat is used.
+	layout string
+}
+
+func (ts timeMarker) label() string {
+	if ts.layout == "" {
+		return ts.time.Format(goAnsicTimeFormat)
+	}
+	return ts.time.Format(ts.layout)
 }
 
 func (ts timeMarker) render(canvas canvas, verticalOffset int, alignment stemAlignment) {
 	for i := 0; i < verticalOffset; i++ {
 		if i == verticalOffset-1 {
-			displayTime := ts.time.Format(goAnsicTimeFormat)
+			displayTime := ts.label()
 			startingOffset := ts.horizontalOffset
 			if alignment == stemAlignmentRight {
 				startingOffset -= len(displayTime) - 1
 			}
+			// Keep the label on the canvas, even if it's then not quite at the end of the stem
+			width := len(canvas.buf[i])
+			if len(displayTime) > width {
+				displayTime = displayTime[:width]
+			}
+			startingOffset = max(0, min(startingOffset, width-len(displayTime)))
 			canvas.put(i, startingOffset, []byte(displayTime))
 		} else {
 			canvas.put(i, ts.horizontalOffset, []byte{'|'})
@@ -0,0 +1,67 @@
+package timefinder
+
+import (
+	"fmt"
+)
+
+// AutoFormat is a pseudo-format that asks for the timestamp format to be detected from a sample of the log.
+const AutoFormat = "auto"
+
+// KnownFormat is a commonly used timestamp format that can be detected automatically.
+type KnownFormat struct {
+	Name   string
+	Layout string
+}
+
+// KnownFormats lists the formats that DetectTimeFormat considers. When two formats match a sample equally well, the
+// one that appears first wins.
+var KnownFormats = []KnownFormat{
+	{Name: "RFC3339", Layout: "2006-01-02T15:04:05.999999999Z07:00"},
+	{Name: "ISO8601 with numeric offset", Layout: "2006-01-02T15:04:05.999999999-0700"},
+	{Name: "ISO8601 without offset", Layout: "2006-01-02T15:04:05.999999999"},
+	{Name: "ISO8601 with space separator", Layout: "2006-01-02 15:04:05.999999999Z07:00"},
+	{Name: "Java log4j / Python logging default", Layout: "2006-01-02 15:04:05,000"},
+	{Name: "ISO8601 with space separator, without offset", Layout: "2006-01-02 15:04:05.999999999"},
+	{Name: "Apache/nginx common log format", Layout: "02/Jan/2006:15:04:05 -0700"},
+	{Name: "HAProxy", Layout: "02/Jan/2006:15:04:05.000"},
+	{Name: "Go ANSIC", Layout: "Mon Jan _2 15:04:05 2006"},
+	{Name: "Go log package default", Layout: "2006/01/02 15:04:05.999999"},
+	{Name: "syslog (RFC3164)", Layout: "Jan _2 15:04:05"},
+	{Name: "Unix epoch seconds", Layout: "epoch"},
+	{Name: "Unix epoch milliseconds", Layout: "epoch_ms"},
+}
+
+// DetectTimeFormat chooses the known format that best matches the sample lines. The best format is the one that finds
+// timestamps in the most lines, with ties going to the format whose timestamps cover more of each line (e.g. because
+// they include fractional seconds or a time zone).
+func DetectTimeFormat(lines []string) (KnownFormat, error) {
+	var best KnownFormat
+	bestMatchedLines, bestMatchedLength := 0, 0
+
+	for _, format := range KnownFormats {
+		tf, err := NewTimeFinder(format.Layout)
+		if err != nil {
+			return KnownFormat{}, fmt.Errorf("invalid known format '%s': %v", format.Name, err)
+		}
+
+		matchedLines, matchedLength := 0, 0
+		for _, line := range lines {
+			dateString, _, err := tf.findTimestamp(line)
+			if err != nil {
+				continue
+			}
+			matchedLines++
+			matchedLength += len(dateString)
+		}
+
+		if matchedLines > bestMatchedLines || (matchedLines == bestMatchedLines && matchedLength > bestMatchedLength) {
+			best = format
+			bestMatchedLines, bestMatchedLength = matchedLines, matchedLength
+		}
+	}
+
+	if bestMatchedLines == 0 {
+		return KnownFormat{}, fmt.Errorf("none of the known timestamp formats matched the first %d lines", len(lines))
+	}
+	return best, nil
+}
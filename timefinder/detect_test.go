@@ -0,0 +1,124 @@
+package timefinder
+
+import (
+	"testing"
+)
+
+func TestDetectTimeFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "RFC3339 with fractional seconds",
+			lines: []string{`{"time":"2024-01-02T03:04:05.123456Z","level":"info"}`},
+			want:  "RFC3339",
+		},
+		{
+			name:  "RFC3339 with offset",
+			lines: []string{"2024-01-02T03:04:05+01:00 starting up"},
+			want:  "RFC3339",
+		},
+		{
+			name:  "ISO8601 without offset",
+			lines: []string{"2024-01-02T03:04:05 INFO starting up"},
+			want:  "ISO8601 without offset",
+		},
+		{
+			name:  "Python logging default",
+			lines: []string{"2024-01-02 03:04:05,123 - myapp - INFO - starting up"},
+			want:  "Java log4j / Python logging default",
+		},
+		{
+			name:  "nginx access log",
+			lines: []string{`127.0.0.1 - - [02/Jan/2024:03:04:05 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/8.0"`},
+			want:  "Apache/nginx common log format",
+		},
+		{
+			name:  "HAProxy",
+			lines: []string{sampleLogLine},
+			want:  "HAProxy",
+		},
+		{
+			name:  "Go ANSIC",
+			lines: []string{"Tue Jan  2 03:04:05 2024 starting up", "Sat Nov 23 06:26:40 2019 still going"},
+			want:  "Go ANSIC",
+		},
+		{
+			name:  "Go log package default",
+			lines: []string{"2024/01/02 03:04:05 starting up"},
+			want:  "Go log package default",
+		},
+		{
+			name:  "syslog",
+			lines: []string{"Nov 23 06:26:40 ip-10-1-1-1 sshd[1234]: Accepted publickey for ubuntu"},
+			want:  "syslog (RFC3164)",
+		},
+		{
+			name:  "epoch seconds",
+			lines: []string{`{"ts":1700000000.123,"pid":12345,"bytes":4096,"msg":"hello"}`},
+			want:  "Unix epoch seconds",
+		},
+		{
+			name:  "epoch milliseconds",
+			lines: []string{`{"ts":1700000000123,"msg":"hello"}`},
+			want:  "Unix epoch milliseconds",
+		},
+		{
+			name: "majority wins",
+			lines: []string{
+				"2024/01/02 03:04:05 request id 2024-01-02T03:04:05Z",
+				"2024/01/02 03:04:06 starting up",
+				"2024/01/02 03:04:07 still going",
+			},
+			want: "Go log package default",
+		},
+		{
+			name:    "no timestamps",
+			lines:   []string{"hi mom", "hi dad"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectTimeFormat(tt.lines)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectTimeFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Name != tt.want {
+				t.Errorf("DetectTimeFormat() = %v, want %v", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestKnownFormats(t *testing.T) {
+	for _, format := range KnownFormats {
+		t.Run(format.Name, func(t *testing.T) {
+			if _, err := NewTimeFinder(format.Layout); err != nil {
+				t.Errorf("NewTimeFinder(%q) error = %v", format.Layout, err)
+			}
+		})
+	}
+}
+
+func Test_convertTimeFormatToRegexForKnownFormats(t *testing.T) {
+	for _, format := range KnownFormats {
+		if _, ok := epochFormats[format.Layout]; ok {
+			// Epoch timestamps don't have a layout to format with
+			continue
+		}
+		t.Run(format.Name, func(t *testing.T) {
+			tf, err := NewTimeFinder(format.Layout)
+			if err != nil {
+				t.Fatalf("NewTimeFinder(%q) error = %v", format.Layout, err)
+			}
+			canonical := parseTime("23/Nov/2019:16:26:40.781").Format(format.Layout)
+			if match := tf.timeRegex.FindString("prefix " + canonical + " suffix"); match != canonical {
+				t.Errorf("regex %v matched %q in %q", tf.timeRegex, match, canonical)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package timefinder
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// epochFormat describes a pseudo-format for timestamps that count units of time since the Unix epoch.
+type epochFormat struct {
+	unit time.Duration
+	// digits is the number of digits in the integer part of the timestamp. Every timestamp since September 2001 has
+	// exactly this many digits (until the year 2286), so numbers of other lengths are ignored.
+	digits int
+}
+
+var epochFormats = map[string]epochFormat{
+	"epoch":    {unit: time.Second, digits: 10},
+	"epoch_ms": {unit: time.Millisecond, digits: 13},
+	"epoch_us": {unit: time.Microsecond, digits: 16},
+	"epoch_ns": {unit: time.Nanosecond, digits: 19},
+}
+
+// maxEpochFutureOffset is how far past the current time an epoch timestamp can be. Larger numbers are more likely to
+// be something else, like a byte count.
+const maxEpochFutureOffset = 366 * 24 * time.Hour
+
+func newEpochRegex(format epochFormat) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`\d{%d}(?:\.\d+)?`, format.digits))
+}
+
+// findEpochTimestamp returns the first number in the line that looks like an epoch timestamp. To avoid mistaking
+// other numbers (PIDs, byte counts, IP addresses, version numbers) for timestamps, a candidate must have the right
+// number of digits, must not be part of a longer number or dotted sequence, and must not be too far in the future.
+func findEpochTimestamp(s string, format epochFormat, regex *regexp.Regexp, latest time.Time) (string, time.Time, error) {
+	for _, loc := range regex.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if start > 0 && isDigitOrDot(s[start-1]) {
+			continue
+		}
+		if end < len(s) && isDigitOrDot(s[end]) {
+			continue
+		}
+
+		t, err := parseEpoch(s[start:end], format.unit)
+		if err != nil || t.After(latest) {
+			continue
+		}
+		return s[start:end], t, nil
+	}
+
+	return "", time.Time{}, fmt.Errorf("couldn't find time in line '%s'", s)
+}
+
+// parseEpoch converts a number of units since the epoch, possibly with a fractional part, to a time.
+func parseEpoch(s string, unit time.Duration) (time.Time, error) {
+	integer, fraction := s, ""
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' {
+			integer, fraction = s[:i], s[i+1:]
+			break
+		}
+	}
+
+	units, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nanos := units * int64(unit)
+	if nanos/int64(unit) != units {
+		return time.Time{}, fmt.Errorf("epoch timestamp '%s' is out of range", s)
+	}
+
+	if fraction != "" {
+		// Scale the fraction to billionths of a unit, then convert to nanoseconds
+		fraction = (fraction + "000000000")[:9]
+		billionths, err := strconv.ParseInt(fraction, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		nanos += billionths * int64(unit) / int64(time.Second)
+	}
+
+	return time.Unix(0, nanos).UTC(), nil
+}
+
+func isDigitOrDot(b byte) bool {
+	return b == '.' || ('0' <= b && b <= '9')
+}
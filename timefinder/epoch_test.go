@@ -0,0 +1,98 @@
+package timefinder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeFinder_epochFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		line    string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:   "seconds",
+			format: "epoch",
+			line:   `{"ts":1700000000,"msg":"hello"}`,
+			want:   time.Unix(1700000000, 0),
+		},
+		{
+			name:   "seconds with fraction",
+			format: "epoch",
+			line:   `{"ts":1700000000.123,"msg":"hello"}`,
+			want:   time.Unix(1700000000, 123000000),
+		},
+		{
+			name:   "milliseconds",
+			format: "epoch_ms",
+			line:   "ts=1700000000123 level=info",
+			want:   time.Unix(1700000000, 123000000),
+		},
+		{
+			name:   "milliseconds with fraction",
+			format: "epoch_ms",
+			line:   "ts=1700000000123.456 level=info",
+			want:   time.Unix(1700000000, 123456000),
+		},
+		{
+			name:   "microseconds",
+			format: "epoch_us",
+			line:   "1700000000123456 GET /",
+			want:   time.Unix(1700000000, 123456000),
+		},
+		{
+			name:   "nanoseconds",
+			format: "epoch_ns",
+			line:   "GET / 1700000000123456789",
+			want:   time.Unix(1700000000, 123456789),
+		},
+		{
+			name:   "skips PIDs and byte counts",
+			format: "epoch",
+			line:   "pid=12345 bytes=4096 ts=1700000000",
+			want:   time.Unix(1700000000, 0),
+		},
+		{
+			name:   "skips numbers that are too far in the future",
+			format: "epoch",
+			line:   "bytes=9999999999 ts=1700000000",
+			want:   time.Unix(1700000000, 0),
+		},
+		{
+			name:   "skips parts of longer numbers",
+			format: "epoch",
+			line:   "id=170000000012 ts=1700000000",
+			want:   time.Unix(1700000000, 0),
+		},
+		{
+			name:    "skips dotted sequences",
+			format:  "epoch",
+			line:    "version 1.1700000000.2",
+			wantErr: true,
+		},
+		{
+			name:    "for a line without a timestamp, returns an error",
+			format:  "epoch_ms",
+			line:    "ts=1700000000 level=info",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := NewTimeFinder(tt.format)
+			if err != nil {
+				t.Fatalf("unexpected NewTimeFinder() error = %v", err)
+			}
+			got, err := tf.findFirstTimestamp(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findFirstTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("findFirstTimestamp() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package timefinder
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Fields are the fields of a structured log line, like a JSON object or a logfmt line. Nested objects are
+// map[string]any.
+type Fields map[string]any
+
+// FieldParser parses a structured log line into its fields. It returns an error if the line isn't structured the way
+// that it expects.
+type FieldParser func(line string) (Fields, error)
+
+// Field returns the text of the field at path, which is a key or a dotted sequence of keys like "http.status" that
+// leads into nested objects. Keys that contain dots themselves, like "log.level" in {"log.level": "info"}, are found
+// too. Strings are returned as they are, and numbers, booleans, objects, and arrays as JSON. The second return value
+// is false if there isn't a field at the path or if it's null.
+func (f Fields) Field(path string) (string, bool) {
+	value, ok := f.lookup(path)
+	if !ok || value == nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		var b bytes.Buffer
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err != nil {
+			return "", false
+		}
+		return strings.TrimSuffix(b.String(), "\n"), true
+	}
+}
+
+func (f Fields) lookup(path string) (any, bool) {
+	if value, ok := f[path]; ok {
+		return value, true
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		// Nested objects are decoded as map[string]any rather than Fields
+		if nested, ok := f[path[:i]].(map[string]any); ok {
+			if value, ok := Fields(nested).lookup(path[i+1:]); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,133 @@
+package timefinder
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFields_Field(t *testing.T) {
+	fields, err := ParseJSON(`{"time": "2019-11-23T06:26:40Z", "status": 503, "duration": 1.50, "ok": false, "empty": null,` +
+		`"http": {"request": {"method": "GET", "path": "/a&b"}}, "log.level": "warn", "tags": ["a", "b"]}`)
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		want   string
+		wantOk bool
+	}{
+		{"time", "2019-11-23T06:26:40Z", true},
+		{"status", "503", true},
+		{"duration", "1.50", true},
+		{"ok", "false", true},
+		{"empty", "", false},
+		{"http.request.method", "GET", true},
+		{"http.request", `{"method":"GET","path":"/a&b"}`, true},
+		{"log.level", "warn", true},
+		{"tags", `["a","b"]`, true},
+		{"missing", "", false},
+		{"http.response.status", "", false},
+		{"time.zone", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := fields.Field(tt.path)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Field() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestTimeFinder_SetTimeField(t *testing.T) {
+	first := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC)
+	tests := []struct {
+		name          string
+		format        string
+		parseFields   FieldParser
+		field         string
+		lines         []string
+		want          []time.Time
+		wantUnmatched int
+	}{
+		{
+			name:        "ignores timestamps outside of the field",
+			format:      time.RFC3339,
+			parseFields: ParseJSON,
+			field:       "time",
+			lines: []string{
+				`{"time": "2019-11-23T06:26:40Z", "msg": "started at 2019-11-22T00:00:00Z"}`,
+				`{"msg": "2019-11-23T06:26:41Z"}`,
+			},
+			want:          []time.Time{first},
+			wantUnmatched: 1,
+		},
+		{
+			name:        "nested field",
+			format:      time.RFC3339,
+			parseFields: ParseJSON,
+			field:       "event.created",
+			lines:       []string{`{"event": {"created": "2019-11-23T06:26:40Z"}}`},
+			want:        []time.Time{first},
+		},
+		{
+			name:        "epoch number",
+			format:      "epoch_ms",
+			parseFields: ParseJSON,
+			field:       "ts",
+			lines:       []string{`{"ts": 1574490400000}`, `{"ts": 1574490401000.5}`},
+			want:        []time.Time{first, first.Add(time.Second + 500*time.Microsecond)},
+		},
+		{
+			name:          "lines that aren't JSON objects are unmatched",
+			format:        time.RFC3339,
+			parseFields:   ParseJSON,
+			field:         "time",
+			lines:         []string{`2019-11-23T06:26:40Z hi mom`, `{"time": "2019-11-23T06:26:40Z"}`},
+			want:          []time.Time{first},
+			wantUnmatched: 1,
+		},
+		{
+			name:          "logfmt",
+			format:        time.RFC3339,
+			parseFields:   ParseLogfmt,
+			field:         "ts",
+			lines:         []string{`ts=2019-11-23T06:26:40Z level=info msg="retry at 2019-11-23T06:30:00Z"`, `level=info msg=hi`},
+			want:          []time.Time{first},
+			wantUnmatched: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := NewTimeFinder(tt.format)
+			if err != nil {
+				t.Fatalf("NewTimeFinder() error = %v", err)
+			}
+			tf.SetTimeField(tt.parseFields, tt.field)
+
+			var got []int64
+			stats, err := tf.ForEachLine(strings.NewReader(strings.Join(tt.lines, "\n")), func(timestamp int64, _ string, fields Fields) {
+				if fields == nil {
+					t.Errorf("ForEachLine() didn't give the line's fields")
+				}
+				got = append(got, timestamp)
+			})
+			if err != nil {
+				t.Fatalf("ForEachLine() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ForEachLine() got %v timestamps, want %v", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i].UnixNano() {
+					t.Errorf("timestamp %d = %v, want %v", i, time.Unix(0, got[i]).UTC(), tt.want[i])
+				}
+			}
+			if stats.Unmatched != tt.wantUnmatched {
+				t.Errorf("Unmatched = %v, want %v", stats.Unmatched, tt.wantUnmatched)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package timefinder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LineFilter decides which lines are scanned for timestamps. A line passes the filter if it matches at least one of the
+// include patterns (or there aren't any) and none of the exclude patterns.
+type LineFilter struct {
+	include []lineMatcher
+	exclude []lineMatcher
+}
+
+// lineMatcher reports whether a line matches a pattern. The line's fields are nil unless the lines are structured.
+type lineMatcher func(line string, fields Fields) bool
+
+// NewLineFilter compiles the include and exclude patterns, which are regular expressions. Patterns without any special
+// characters are matched as fixed strings, which is much faster.
+func NewLineFilter(include, exclude []string) (*LineFilter, error) {
+	return newFilter(include, exclude, compileLineMatcher)
+}
+
+// NewFieldFilter is like NewLineFilter, but for structured lines. Each pattern has the form FIELD=REGEX, where FIELD is
+// the path of a field (see Fields.Field), and it matches lines where the field exists and its value matches the
+// regular expression.
+func NewFieldFilter(include, exclude []string) (*LineFilter, error) {
+	return newFilter(include, exclude, compileFieldMatcher)
+}
+
+func newFilter(include, exclude []string, compile func(pattern string) (lineMatcher, error)) (*LineFilter, error) {
+	var f LineFilter
+	var err error
+	if f.include, err = compileLineMatchers(include, compile); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compileLineMatchers(exclude, compile); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Allows reports whether the line passes the filter. Its fields must be given if the filter was created with
+// NewFieldFilter.
+func (f *LineFilter) Allows(line string, fields Fields) bool {
+	if len(f.include) > 0 && !matchesAny(f.include, line, fields) {
+		return false
+	}
+	return !matchesAny(f.exclude, line, fields)
+}
+
+func compileLineMatchers(patterns []string, compile func(pattern string) (lineMatcher, error)) ([]lineMatcher, error) {
+	matchers := make([]lineMatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		matcher, err := compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+func compileLineMatcher(pattern string) (lineMatcher, error) {
+	matches, err := compileStringMatcher(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(line string, _ Fields) bool {
+		return matches(line)
+	}, nil
+}
+
+func compileFieldMatcher(pattern string) (lineMatcher, error) {
+	path, valuePattern, ok := strings.Cut(pattern, "=")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("'%s' isn't of the form FIELD=REGEX", pattern)
+	}
+	matches, err := compileStringMatcher(valuePattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ string, fields Fields) bool {
+		value, ok := fields.Field(path)
+		return ok && matches(value)
+	}, nil
+}
+
+func compileStringMatcher(pattern string) (func(s string) bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if literal, complete := re.LiteralPrefix(); complete {
+		return func(s string) bool {
+			return strings.Contains(s, literal)
+		}, nil
+	}
+	return re.MatchString, nil
+}
+
+func matchesAny(matchers []lineMatcher, line string, fields Fields) bool {
+	for _, matches := range matchers {
+		if matches(line, fields) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package timefinder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		line    string
+		want    bool
+	}{
+		{"no patterns", nil, nil, "GET /health 200", true},
+		{"matches a fixed string", []string{"GET"}, nil, "GET /health 200", true},
+		{"doesn't match a fixed string", []string{"POST"}, nil, "GET /health 200", false},
+		{"matches a regular expression", []string{` 5\d\d$`}, nil, "GET /api 503", true},
+		{"doesn't match a regular expression", []string{` 5\d\d$`}, nil, "GET /api 200", false},
+		{"matches any of several patterns", []string{"POST", "GET"}, nil, "GET /api 200", true},
+		{"excluded", nil, []string{"/health"}, "GET /health 200", false},
+		{"not excluded", nil, []string{"/health"}, "GET /api 200", true},
+		{"matched but excluded", []string{"GET"}, []string{"/health"}, "GET /health 200", false},
+		{"escaped special characters are fixed strings", []string{`/api\?v=1`}, nil, "GET /api?v=1 200", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewLineFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("NewLineFilter() error = %v", err)
+			}
+			if got := f.Allows(tt.line, nil); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLineFilter_invalidPattern(t *testing.T) {
+	if _, err := NewLineFilter([]string{"("}, nil); err == nil {
+		t.Error("NewLineFilter() expected an error for an invalid pattern but didn't get one")
+	}
+}
+
+func TestFieldFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		line    string
+		want    bool
+	}{
+		{"matches a field", []string{"level=error"}, nil, `{"level": "error", "msg": "disk full"}`, true},
+		{"doesn't match a field", []string{"level=error"}, nil, `{"level": "info", "msg": "error budget ok"}`, false},
+		{"matches a nested field", []string{`http.status=^5\d\d$`}, nil, `{"http": {"status": 503}}`, true},
+		{"missing field", []string{"level=error"}, nil, `{"msg": "error"}`, false},
+		{"regular expression with an equals sign", []string{"query=^a=b"}, nil, `{"query": "a=b&c=d"}`, true},
+		{"excluded", nil, []string{"path=/health"}, `{"path": "/health"}`, false},
+		{"missing field isn't excluded", nil, []string{"path=/health"}, `{"msg": "/health"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFieldFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("NewFieldFilter() error = %v", err)
+			}
+			fields, err := ParseJSON(tt.line)
+			if err != nil {
+				t.Fatalf("ParseJSON() error = %v", err)
+			}
+			if got := f.Allows(tt.line, fields); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFieldFilter_invalidPattern(t *testing.T) {
+	for _, pattern := range []string{"error", "=error", "level=("} {
+		if _, err := NewFieldFilter([]string{pattern}, nil); err == nil {
+			t.Errorf("NewFieldFilter() expected an error for %q but didn't get one", pattern)
+		}
+	}
+}
+
+func TestTimeFinder_SetLineFilter(t *testing.T) {
+	tf, err := NewTimeFinder(apacheCommonLogFormatDate)
+	if err != nil {
+		t.Fatalf("NewTimeFinder() error = %v", err)
+	}
+	filter, err := NewLineFilter([]string{"GET"}, []string{"health"})
+	if err != nil {
+		t.Fatalf("NewLineFilter() error = %v", err)
+	}
+	tf.SetLineFilter(filter)
+
+	lines := "[23/Nov/2019:06:26:40.000] GET /api\n[23/Nov/2019:06:26:41.000] GET /health\n[23/Nov/2019:06:26:42.000] POST /api\n"
+	got, err := tf.ExtractTimestampFromEachLine(strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("ExtractTimestampFromEachLine() error = %v", err)
+	}
+	if want := parseTime("23/Nov/2019:06:26:40.000").UnixNano(); len(got) != 1 || got[0] != want {
+		t.Errorf("ExtractTimestampFromEachLine() got = %v, want [%v]", got, want)
+	}
+}
+
+func Benchmark_LineFilter(b *testing.B) {
+	for _, pattern := range []string{"myapp/i-05fa49c0e7db8c328", `myapp/i-\w+`} {
+		b.Run(pattern, func(b *testing.B) {
+			f, err := NewLineFilter([]string{pattern}, nil)
+			if err != nil {
+				b.Fatalf("NewLineFilter() error = %v", err)
+			}
+			for i := 0; i < b.N; i++ {
+				f.Allows(sampleLogLine, nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package timefinder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseJSON is a FieldParser for JSON Lines logs, where each line is a JSON object. Numbers keep the text that they
+// were written with, so that timestamps and other values aren't rounded.
+func ParseJSON(line string) (Fields, error) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	var fields Fields
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %v", err)
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	return fields, nil
+}
@@ -0,0 +1,27 @@
+package timefinder
+
+import "testing"
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantErr bool
+	}{
+		{"object", `{"time": "2019-11-23T06:26:40Z", "msg": "hi mom"}`, false},
+		{"object with trailing newline", "{\"time\": 1574490400}\n", false},
+		{"array", `["2019-11-23T06:26:40Z"]`, true},
+		{"string", `"2019-11-23T06:26:40Z"`, true},
+		{"null", `null`, true},
+		{"not JSON", `2019-11-23T06:26:40Z hi mom`, true},
+		{"truncated", `{"time": "2019-11-23T06:26:40Z", "msg": "hi`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJSON(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
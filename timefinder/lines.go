@@ -0,0 +1,50 @@
+package timefinder
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// DefaultMaxLineLength is the length, in bytes, of the longest line that's scanned for a timestamp unless
+// SetMaxLineLength is used to change it.
+const DefaultMaxLineLength = 1 << 20
+
+// initialLineBufferSize is the size of the buffer that lines are read into at first. It grows as needed, up to the
+// maximum line length.
+const initialLineBufferSize = 64 * 1024
+
+// newLineScanner returns a scanner that reads lines like bufio.ScanLines does, except that lines longer than maxLength
+// are skipped rather than stopping the scan. Each skipped line is counted in longLines.
+func newLineScanner(r io.Reader, maxLength int, longLines *int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(initialLineBufferSize, maxLength+1)), maxLength+1)
+
+	// skipping is true while the rest of a long line is being discarded
+	skipping := false
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		end := bytes.IndexByte(data, '\n')
+		switch {
+		case end >= 0:
+			wasSkipping := skipping
+			skipping = false
+			if wasSkipping {
+				return end + 1, nil, nil
+			}
+			if end > maxLength {
+				*longLines++
+				return end + 1, nil, nil
+			}
+			return bufio.ScanLines(data, atEOF)
+		case skipping:
+			return len(data), nil, nil
+		case len(data) > maxLength:
+			*longLines++
+			skipping = !atEOF
+			return len(data), nil, nil
+		default:
+			return bufio.ScanLines(data, atEOF)
+		}
+	})
+	return scanner
+}
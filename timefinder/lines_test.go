@@ -0,0 +1,68 @@
+package timefinder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_newLineScanner(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		maxLength     int
+		wantLines     []string
+		wantLongLines int
+	}{
+		{"short lines", "one\ntwo\n", 10, []string{"one", "two"}, 0},
+		{"no trailing newline", "one\ntwo", 10, []string{"one", "two"}, 0},
+		{"carriage returns", "one\r\ntwo\r\n", 10, []string{"one", "two"}, 0},
+		{"line of the maximum length", "0123456789\nend\n", 10, []string{"0123456789", "end"}, 0},
+		{"long line", "one\n01234567890\nend\n", 10, []string{"one", "end"}, 1},
+		{"long line without a trailing newline", "one\n01234567890", 10, []string{"one"}, 1},
+		{"long lines that span several reads", "one\n" + strings.Repeat("x", 100) + "\n" + strings.Repeat("y", 100) + "\nend", 10, []string{"one", "end"}, 2},
+		{"line longer than the initial buffer", strings.Repeat("x", 2*initialLineBufferSize) + "\nend\n", DefaultMaxLineLength, []string{strings.Repeat("x", 2*initialLineBufferSize), "end"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			longLines := 0
+			scanner := newLineScanner(strings.NewReader(tt.input), tt.maxLength, &longLines)
+			var lines []string
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("lines = %q, want %q", lines, tt.wantLines)
+			}
+			if longLines != tt.wantLongLines {
+				t.Errorf("longLines = %v, want %v", longLines, tt.wantLongLines)
+			}
+		})
+	}
+}
+
+func TestTimeFinder_ForEachTimestampWithLongLines(t *testing.T) {
+	tf, err := NewTimeFinder(apacheCommonLogFormatDate)
+	if err != nil {
+		t.Fatalf("NewTimeFinder() error = %v", err)
+	}
+	tf.SetMaxLineLength(1000)
+
+	lines := sampleLogLine + "\n" + sampleLogLine + strings.Repeat(" padding", 200) + "\n" + sampleLogLine + "\n"
+	count := 0
+	stats, err := tf.ForEachTimestamp(strings.NewReader(lines), func(int64) {
+		count++
+	})
+	if err != nil {
+		t.Fatalf("ForEachTimestamp() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("found %d timestamps, want 2", count)
+	}
+	if stats.LongLines != 1 {
+		t.Errorf("stats.LongLines = %v, want 1", stats.LongLines)
+	}
+}
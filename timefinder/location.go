@@ -0,0 +1,88 @@
+package timefinder
+
+import "time"
+
+// wallClockLayout is used to compare the wall clock times of two times, ignoring their time zones.
+const wallClockLayout = "2006-01-02T15:04:05.999999999"
+
+// zoneInference resolves timestamps whose format doesn't include a UTC offset, which are read as wall clock times in
+// some location. When the clocks are set back at the end of daylight saving time, an hour of wall clock times occurs
+// twice. Each timestamp in that hour is placed in the earliest occurrence that isn't before the timestamp before it, or
+// else in whichever occurrence is closest to it, so the repeated hour isn't folded onto the first one.
+type zoneInference struct {
+	// tolerance is how far a timestamp can go back in time and still be considered to follow the one before it
+	tolerance time.Duration
+	last      time.Time
+}
+
+func (z *zoneInference) apply(t time.Time) time.Time {
+	resolved := t
+	if !z.last.IsZero() {
+		occurrences := append([]time.Time{t}, sameWallClockTimes(t)...)
+		earliest := time.Time{}
+		for _, occurrence := range occurrences {
+			if absDuration(occurrence.Sub(z.last)) < absDuration(resolved.Sub(z.last)) {
+				resolved = occurrence
+			}
+			if !occurrence.Before(z.last.Add(-z.tolerance)) && (earliest.IsZero() || occurrence.Before(earliest)) {
+				earliest = occurrence
+			}
+		}
+		if !earliest.IsZero() {
+			resolved = earliest
+		}
+	}
+
+	z.last = resolved
+	return resolved
+}
+
+// sameWallClockTimes returns the other times, if any, that have the same wall clock time as t in t's location.
+func sameWallClockTimes(t time.Time) []time.Time {
+	if t.Location() == time.UTC {
+		return nil
+	}
+	_, offsetBefore := t.Add(-24 * time.Hour).Zone()
+	_, offsetAfter := t.Add(24 * time.Hour).Zone()
+	if offsetBefore == offsetAfter {
+		return nil
+	}
+
+	shift := time.Duration(offsetBefore-offsetAfter) * time.Second
+	var times []time.Time
+	for _, candidate := range []time.Time{t.Add(shift), t.Add(-shift)} {
+		if candidate.Format(wallClockLayout) == t.Format(wallClockLayout) {
+			times = append(times, candidate)
+		}
+	}
+	return times
+}
+
+// observesDaylightSavingTime reports whether the location's UTC offset changes during the current year.
+func observesDaylightSavingTime(location *time.Location) bool {
+	if location == nil || location == time.UTC {
+		return false
+	}
+	year := time.Now().Year()
+	_, januaryOffset := time.Date(year, time.January, 1, 0, 0, 0, 0, location).Zone()
+	_, julyOffset := time.Date(year, time.July, 1, 0, 0, 0, 0, location).Zone()
+	return januaryOffset != julyOffset
+}
+
+// formatHasOffset reports whether timestamps in the format include their UTC offset or time zone.
+func formatHasOffset(dateFormat string) bool {
+	zone := time.FixedZone("", -7*60*60)
+	t, err := time.Parse(dateFormat, time.Date(2006, 1, 2, 15, 4, 5, 0, zone).Format(dateFormat))
+	if err != nil {
+		return false
+	}
+	_, offset := t.Zone()
+	return offset != 0 || t.Location() != time.UTC
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
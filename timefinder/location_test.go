@@ -0,0 +1,135 @@
+package timefinder
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_formatHasOffset(t *testing.T) {
+	tests := []struct {
+		format string
+		want   bool
+	}{
+		{time.RFC3339, true},
+		{"2006-01-02T15:04:05-0700", true},
+		{"02/Jan/2006:15:04:05 -0700", true},
+		{"2006-01-02 15:04:05", false},
+		{apacheCommonLogFormatDate, false},
+		{"Jan _2 15:04:05", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := formatHasOffset(tt.format); got != tt.want {
+				t.Errorf("formatHasOffset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeFinder_SetLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database isn't available: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		lines     []string
+		tolerance time.Duration
+		want      []time.Time
+	}{
+		{
+			name:  "reads timestamps in the location",
+			lines: []string{"2019-11-23 06:26:40 hi mom"},
+			want:  []time.Time{time.Date(2019, time.November, 23, 11, 26, 40, 0, time.UTC)},
+		},
+		{
+			name: "places timestamps in a repeated hour after the timestamps before them",
+			lines: []string{
+				"2019-11-03 01:50:00 EDT",
+				"2019-11-03 01:10:00 EST",
+				"2019-11-03 01:30:00 EST",
+				"2019-11-03 02:00:00 EST",
+			},
+			want: []time.Time{
+				time.Date(2019, time.November, 3, 5, 50, 0, 0, time.UTC),
+				time.Date(2019, time.November, 3, 6, 10, 0, 0, time.UTC),
+				time.Date(2019, time.November, 3, 6, 30, 0, 0, time.UTC),
+				time.Date(2019, time.November, 3, 7, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "places sparse timestamps in a repeated hour in the earliest occurrence after the timestamps before them",
+			lines: []string{
+				"2019-11-03 01:10:00 EDT",
+				"2019-11-03 01:50:00 EDT",
+				"2019-11-03 01:10:00 EST",
+				"2019-11-03 01:50:00 EST",
+			},
+			want: []time.Time{
+				time.Date(2019, time.November, 3, 5, 10, 0, 0, time.UTC),
+				time.Date(2019, time.November, 3, 5, 50, 0, 0, time.UTC),
+				time.Date(2019, time.November, 3, 6, 10, 0, 0, time.UTC),
+				time.Date(2019, time.November, 3, 6, 50, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:      "keeps timestamps that go back in time by less than the tolerance in the same occurrence",
+			lines:     []string{"2019-11-03 01:50:00 EDT", "2019-11-03 01:49:59"},
+			tolerance: 2 * time.Second,
+			want: []time.Time{
+				time.Date(2019, time.November, 3, 5, 50, 0, 0, time.UTC),
+				time.Date(2019, time.November, 3, 5, 49, 59, 0, time.UTC),
+			},
+		},
+		{
+			name:  "leaves timestamps around a skipped hour one minute apart",
+			lines: []string{"2019-03-10 01:59:00", "2019-03-10 03:00:00"},
+			want: []time.Time{
+				time.Date(2019, time.March, 10, 6, 59, 0, 0, time.UTC),
+				time.Date(2019, time.March, 10, 7, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := NewTimeFinder("2006-01-02 15:04:05")
+			if err != nil {
+				t.Fatalf("NewTimeFinder() error = %v", err)
+			}
+			tf.SetLocation(newYork)
+			tf.SetReorderTolerance(tt.tolerance)
+
+			got, err := tf.ExtractTimestampFromEachLine(strings.NewReader(strings.Join(tt.lines, "\n")))
+			if err != nil {
+				t.Fatalf("ExtractTimestampFromEachLine() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ExtractTimestampFromEachLine() got = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i].UnixNano() {
+					t.Errorf("timestamp %d = %v, want %v", i, time.Unix(0, got[i]).UTC(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTimeFinder_locationWithOffsetFormat(t *testing.T) {
+	tf, err := NewTimeFinder(time.RFC3339)
+	if err != nil {
+		t.Fatalf("NewTimeFinder() error = %v", err)
+	}
+	tf.SetLocation(time.FixedZone("UTC-5", -5*60*60))
+
+	got, err := tf.ExtractTimestampFromEachLine(strings.NewReader("2019-11-23T06:26:40Z hi mom"))
+	if err != nil {
+		t.Fatalf("ExtractTimestampFromEachLine() error = %v", err)
+	}
+	want := time.Date(2019, time.November, 23, 6, 26, 40, 0, time.UTC).UnixNano()
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("ExtractTimestampFromEachLine() got = %v, want [%v]", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package timefinder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseLogfmt is a FieldParser for logfmt logs, where each line is a sequence of key=value pairs separated by spaces,
+// like `ts=2024-01-02T03:04:05Z level=info msg="hi mom"`. Values can be quoted to include spaces, and quoted values can
+// contain escapes like \" and \n. A key without a value is a flag whose value is "true", and a key followed by an
+// equals sign but no value has an empty value. A line must have at least one key=value pair.
+func ParseLogfmt(line string) (Fields, error) {
+	fields := make(Fields)
+	hasPair := false
+	s := strings.TrimRight(line, "\r\n")
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+
+		keyEnd := strings.IndexAny(s, "= \t")
+		if keyEnd == -1 {
+			keyEnd = len(s)
+		}
+		key := s[:keyEnd]
+		if key == "" {
+			return nil, fmt.Errorf("not logfmt: missing key before '='")
+		}
+		s = s[keyEnd:]
+		if !strings.HasPrefix(s, "=") {
+			fields[key] = "true"
+			continue
+		}
+		s = s[1:]
+		hasPair = true
+
+		if !strings.HasPrefix(s, `"`) {
+			valueEnd := strings.IndexAny(s, " \t")
+			if valueEnd == -1 {
+				valueEnd = len(s)
+			}
+			fields[key] = s[:valueEnd]
+			s = s[valueEnd:]
+			continue
+		}
+
+		valueEnd := closingQuote(s)
+		if valueEnd == -1 {
+			return nil, fmt.Errorf("not logfmt: unterminated quoted value for '%s'", key)
+		}
+		value, err := strconv.Unquote(s[:valueEnd+1])
+		if err != nil {
+			// Not every logger escapes the way Go does, so keep the quoted text as it is
+			value = s[1:valueEnd]
+		}
+		fields[key] = value
+		s = s[valueEnd+1:]
+	}
+
+	if !hasPair {
+		return nil, fmt.Errorf("not logfmt: no key=value pairs")
+	}
+	return fields, nil
+}
+
+// closingQuote returns the index of the quote that ends the quoted value at the start of s, or -1 if there isn't one.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,61 @@
+package timefinder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Fields
+		wantErr bool
+	}{
+		{
+			name: "simple values",
+			line: "ts=2024-01-02T03:04:05Z level=info status=200",
+			want: Fields{"ts": "2024-01-02T03:04:05Z", "level": "info", "status": "200"},
+		},
+		{
+			name: "quoted values",
+			line: `level=warn msg="disk \"/var\" is 90% full" path="C:\\logs"`,
+			want: Fields{"level": "warn", "msg": `disk "/var" is 90% full`, "path": `C:\logs`},
+		},
+		{
+			name: "quoted value with an escape that Go doesn't know",
+			line: `msg="a \q b" level=info`,
+			want: Fields{"msg": `a \q b`, "level": "info"},
+		},
+		{
+			name: "flags and empty values",
+			line: "level=debug cached err=  retry",
+			want: Fields{"level": "debug", "cached": "true", "err": "", "retry": "true"},
+		},
+		{
+			name: "dotted keys, extra spaces, and a trailing newline",
+			line: "  http.status=503\t  ts=2024-01-02T03:04:05Z \n",
+			want: Fields{"http.status": "503", "ts": "2024-01-02T03:04:05Z"},
+		},
+		{
+			name: "later values replace earlier ones",
+			line: "level=info level=error",
+			want: Fields{"level": "error"},
+		},
+		{name: "plain text", line: "hi mom", wantErr: true},
+		{name: "empty line", line: "", wantErr: true},
+		{name: "missing key", line: "level=info =oops", wantErr: true},
+		{name: "unterminated quote", line: `msg="hi mom`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLogfmt(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLogfmt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLogfmt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
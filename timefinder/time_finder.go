@@ -1,7 +1,6 @@
 package timefinder
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"regexp"
@@ -17,10 +16,87 @@ const (
 type TimeFinder struct {
 	timeFormat string
 	timeRegex  *regexp.Regexp
+	timeRange  TimeRange
+	yearless   bool
+	years      yearInference
+	epoch      *epochFormat
+	// epochLatest is the latest time that a number can represent and still be considered an epoch timestamp
+	epochLatest time.Time
+	// location is where timestamps without a UTC offset were written. If it's nil, UTC is assumed.
+	location  *time.Location
+	hasOffset bool
+	zones     zoneInference
+	// maxLineLength is the length of the longest line that's scanned. If it's zero, DefaultMaxLineLength is used.
+	maxLineLength int
+	// filter decides which lines are scanned. If it's nil, every line is scanned.
+	filter *LineFilter
+	// parseFields parses each line into fields, and timeField is the path of the field that holds the timestamp. If
+	// parseFields is nil, the lines aren't structured and the timestamp can be anywhere in them.
+	parseFields FieldParser
+	timeField   string
 }
 
-// NewTimeFinder constructs a new TimeFinder instance. It returns an error if the time format is invalid.
+const (
+	// maxUnmatchedSamples is the number of lines without a timestamp that are kept in ScanStats as examples.
+	maxUnmatchedSamples = 5
+	// maxUnmatchedSampleLength is the length, in bytes, that samples are truncated to.
+	maxUnmatchedSampleLength = 200
+)
+
+// ScanStats describes the lines that were scanned for timestamps.
+type ScanStats struct {
+	// Lines is the total number of lines, including long lines
+	Lines int
+	// Matched is the number of lines with a timestamp in the time range
+	Matched int
+	// Filtered is the number of lines that didn't pass the line filter
+	Filtered int
+	// Unmatched is the number of lines without a timestamp
+	Unmatched int
+	// UnmatchedSamples holds the first few lines without a timestamp
+	UnmatchedSamples []string
+	// OutOfRange is the number of lines with a timestamp outside of the time range
+	OutOfRange int
+	// LongLines is the number of lines that were skipped because they're longer than the maximum line length
+	LongLines int
+}
+
+// Add adds the counts from other, e.g. to combine the stats for several inputs.
+func (s *ScanStats) Add(other ScanStats) {
+	s.Lines += other.Lines
+	s.Matched += other.Matched
+	s.Filtered += other.Filtered
+	s.Unmatched += other.Unmatched
+	for _, line := range other.UnmatchedSamples {
+		s.addUnmatchedSample(line)
+	}
+	s.OutOfRange += other.OutOfRange
+	s.LongLines += other.LongLines
+}
+
+func (s *ScanStats) addUnmatchedSample(line string) {
+	if len(s.UnmatchedSamples) >= maxUnmatchedSamples {
+		return
+	}
+	if len(line) > maxUnmatchedSampleLength {
+		line = strings.ToValidUTF8(line[:maxUnmatchedSampleLength], "")
+	}
+	s.UnmatchedSamples = append(s.UnmatchedSamples, line)
+}
+
+// NewTimeFinder constructs a new TimeFinder instance. It returns an error if the time format is invalid. Besides the
+// layouts accepted by time.Parse, the time format can be one of the pseudo-formats "epoch", "epoch_ms", "epoch_us", or
+// "epoch_ns" for timestamps that count seconds, milliseconds, microseconds, or nanoseconds since the Unix epoch.
 func NewTimeFinder(timeFormat string) (*TimeFinder, error) {
+	if format, ok := epochFormats[timeFormat]; ok {
+		return &TimeFinder{
+			timeFormat:  timeFormat,
+			timeRegex:   newEpochRegex(format),
+			epoch:       &format,
+			epochLatest: time.Now().Add(maxEpochFutureOffset),
+		}, nil
+	}
+
 	formatRegexString := convertTimeFormatToRegex(timeFormat)
 	formatRegex, err := regexp.Compile(formatRegexString)
 	if err != nil {
@@ -32,24 +108,154 @@ func NewTimeFinder(timeFormat string) (*TimeFinder, error) {
 	return &TimeFinder{
 		timeFormat: timeFormat,
 		timeRegex:  formatRegex,
+		yearless:   !formatHasYear(timeFormat),
+		years: yearInference{
+			reference: time.Now(),
+		},
+		hasOffset: formatHasOffset(timeFormat),
 	}, nil
 }
 
+// SetReferenceTime sets the time that's used to infer the year when the time format doesn't include one. The first
+// timestamp is assumed to be within the year before the reference time, so a good choice is the time when the log was
+// last modified. The default is the current time.
+func (tf *TimeFinder) SetReferenceTime(reference time.Time) {
+	tf.years.reference = reference
+}
+
+// SetYear sets the year of the first timestamp when the time format doesn't include one. It takes precedence over the
+// reference time.
+func (tf *TimeFinder) SetYear(year int) {
+	tf.years.year = year
+}
+
+// SetLocation sets the location that timestamps without a UTC offset are assumed to be in. The default is UTC.
+func (tf *TimeFinder) SetLocation(location *time.Location) {
+	tf.location = location
+}
+
+// SetReorderTolerance sets how far a timestamp can go back in time and still be considered to follow the one before it.
+// It's used to place timestamps without a UTC offset in a repeated hour at the end of daylight saving time. The
+// default is zero.
+func (tf *TimeFinder) SetReorderTolerance(tolerance time.Duration) {
+	tf.zones.tolerance = tolerance
+}
+
+// SetMaxLineLength sets the length, in bytes, of the longest line that's scanned for a timestamp. Longer lines are
+// skipped and counted in the ScanStats.
+func (tf *TimeFinder) SetMaxLineLength(maxLineLength int) {
+	tf.maxLineLength = maxLineLength
+}
+
+// SetLineFilter limits the lines that are scanned for timestamps to those that pass the filter. Other lines are skipped
+// and counted in the ScanStats.
+func (tf *TimeFinder) SetLineFilter(filter *LineFilter) {
+	tf.filter = filter
+}
+
+// SetTimeField makes the TimeFinder parse each line into fields with parseFields (e.g. ParseJSON or ParseLogfmt) and
+// look for the timestamp in the field at path, which can be a dotted sequence of keys (see Fields.Field), instead of
+// anywhere in the line. Lines that can't be parsed or don't have the field are counted as unmatched.
+func (tf *TimeFinder) SetTimeField(parseFields FieldParser, path string) {
+	tf.parseFields = parseFields
+	tf.timeField = path
+}
+
+// SetTimeRange limits the timestamps that are reported to those within the range. Lines with timestamps outside the
+// range are skipped.
+func (tf *TimeFinder) SetTimeRange(timeRange TimeRange) {
+	tf.timeRange = timeRange
+}
+
+// Copy returns a TimeFinder with the same settings that can be used concurrently with this one. The copy interprets
+// timestamps as though it's starting from the beginning of the input.
+func (tf *TimeFinder) Copy() *TimeFinder {
+	c := *tf
+	c.years.last = time.Time{}
+	c.zones.last = time.Time{}
+	return &c
+}
+
+// IsContextFree reports whether each timestamp can be interpreted without the lines before it, which means that parts
+// of the input can be scanned independently. That isn't the case if the time format doesn't include the year, or if it
+// doesn't include a UTC offset and the location observes daylight saving time.
+func (tf *TimeFinder) IsContextFree() bool {
+	if tf.epoch != nil {
+		return true
+	}
+	return !tf.yearless && (tf.hasOffset || !observesDaylightSavingTime(tf.location))
+}
+
 // ExtractTimestampFromEachLine scans each line of the reader to find a timestamp.  It returns a slice of all the
-// timestamps that were found. If no timestamp is found, then the line is skipped.
-func (tf *TimeFinder) ExtractTimestampFromEachLine(r io.Reader) []int64 {
+// timestamps that were found, in nanoseconds since the Unix epoch. If no timestamp is found, then the line is skipped.
+// An error is returned if the reader fails.
+func (tf *TimeFinder) ExtractTimestampFromEachLine(r io.Reader) ([]int64, error) {
 	times := make([]int64, 0)
 
-	scanner := bufio.NewScanner(r)
+	_, err := tf.ForEachTimestamp(r, func(timestamp int64) {
+		times = append(times, timestamp)
+	})
+
+	return times, err
+}
+
+// ForEachTimestamp scans each line of the reader to find a timestamp and calls timestampFunc with each timestamp, in
+// nanoseconds since the Unix epoch, as soon as it's found. If no timestamp is found, then the line is skipped. Unlike
+// ExtractTimestampFromEachLine, it's suitable for readers that never end. It returns stats about the lines that were
+// scanned, along with an error if the reader fails.
+func (tf *TimeFinder) ForEachTimestamp(r io.Reader, timestampFunc func(timestamp int64)) (ScanStats, error) {
+	return tf.ForEachLine(r, func(timestamp int64, _ string, _ Fields) {
+		timestampFunc(timestamp)
+	})
+}
+
+// ForEachLine is like ForEachTimestamp, but lineFunc is also given the line that the timestamp was found in, along with
+// its fields if the lines are structured (see SetTimeField). Otherwise, fields is nil.
+func (tf *TimeFinder) ForEachLine(r io.Reader, lineFunc func(timestamp int64, line string, fields Fields)) (ScanStats, error) {
+	var stats ScanStats
+	maxLineLength := tf.maxLineLength
+	if maxLineLength <= 0 {
+		maxLineLength = DefaultMaxLineLength
+	}
+
+	scanner := newLineScanner(r, maxLineLength, &stats.LongLines)
 	for scanner.Scan() {
-		t, err := tf.findFirstTimestamp(scanner.Text())
+		stats.Lines++
+		line := scanner.Text()
+		var fields Fields
+		if tf.parseFields != nil {
+			var err error
+			if fields, err = tf.parseFields(line); err != nil {
+				stats.Unmatched++
+				stats.addUnmatchedSample(line)
+				continue
+			}
+		}
+		if tf.filter != nil && !tf.filter.Allows(line, fields) {
+			stats.Filtered++
+			continue
+		}
+		var t time.Time
+		var err error
+		if fields != nil {
+			t, err = tf.findFieldTimestamp(fields)
+		} else {
+			t, err = tf.findFirstTimestamp(line)
+		}
 		if err != nil {
+			stats.Unmatched++
+			stats.addUnmatchedSample(line)
+			continue
+		}
+		if !tf.timeRange.Contains(t) {
+			stats.OutOfRange++
 			continue
 		}
-		times = append(times, t.UTC().Unix())
+		stats.Matched++
+		lineFunc(t.UnixNano(), line, fields)
 	}
-
-	return times
+	stats.Lines += stats.LongLines
+	return stats, scanner.Err()
 }
 
 func checkDateFormatForErrors(dateFormat string) error {
@@ -57,27 +263,52 @@ func checkDateFormatForErrors(dateFormat string) error {
 	if err != nil {
 		return fmt.Errorf("couldn't parse canonical time: %v", err)
 	}
-	t, err := time.Parse(dateFormat, dateFormat)
-	if err != nil || t != canonicalTime {
+	// Round-trip the canonical time through the format. If any part of the time is lost, the format is incomplete.
+	t, err := time.Parse(dateFormat, canonicalTime.Format(dateFormat))
+	if err == nil && t.Year() == 0 {
+		// The year can be inferred, so it's fine for the format to leave it out
+		t = t.AddDate(canonicalTime.Year(), 0, 0)
+	}
+	if err != nil || !t.Equal(canonicalTime) {
 		errorText := fmt.Sprintf("invalid date/time format '%s'", dateFormat)
 
 		if err != nil {
 			errorText += fmt.Sprintf(": %v", err)
 		}
 
-		return fmt.Errorf("%s\n\nThe format must include year, day, and time. Please follow the format described in https://golang.org/pkg/time/#Time.Format\n", errorText)
+		return fmt.Errorf("%s\n\nThe format must include day and time, and should include year. Please follow the format described in https://golang.org/pkg/time/#Time.Format\n", errorText)
 	}
 
 	return nil
 }
 
+// formatHasYear reports whether timestamps in the format include the year.
+func formatHasYear(dateFormat string) bool {
+	t, err := time.Parse(dateFormat, time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(dateFormat))
+	return err == nil && t.Year() != 0
+}
+
 func convertTimeFormatToRegex(format string) string {
+	// Longer elements must come first so that they take precedence over the elements that they contain
 	replaceSet := []string{
+		".999999999", "(?:\\.\\d+)?",
+		".999999", "(?:\\.\\d+)?",
+		".999", "(?:\\.\\d+)?",
+		"Z07:00", "(?:Z|[+-]\\d\\d:\\d\\d)",
+		"Z0700", "(?:Z|[+-]\\d{4})",
+		"-07:00", "[+-]\\d\\d:\\d\\d",
+		"-0700", "[+-]\\d{4}",
+		"MST", "[A-Z]{3,5}",
+		"PM", "[AP]M",
+		"pm", "[ap]m",
 		".", "\\.",
 		"2006", "\\d{4}",
 		"06", "\\d{2}",
+		"January", "[A-Za-z]{3,9}",
 		"Jan", "[A-Za-z]{3}",
-		"January", "[A-Za-z]{3,4,5,6,7,8,9}",
+		"Monday", "[A-Za-z]{6,9}",
+		"Mon", "[A-Za-z]{3}",
+		"_2", "[ \\d]\\d",
 		"0", "\\d",
 		"1", "\\d",
 		"2", "\\d",
@@ -94,9 +325,59 @@ func convertTimeFormatToRegex(format string) string {
 }
 
 func (tf *TimeFinder) findFirstTimestamp(s string) (time.Time, error) {
+	_, t, err := tf.findTimestamp(s)
+	return t, err
+}
+
+// findLineTimestamp returns the timestamp of the line, reading it from the time field if the lines are structured.
+func (tf *TimeFinder) findLineTimestamp(line string) (time.Time, error) {
+	if tf.parseFields == nil {
+		return tf.findFirstTimestamp(line)
+	}
+	fields, err := tf.parseFields(line)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tf.findFieldTimestamp(fields)
+}
+
+// findFieldTimestamp returns the timestamp in the time field of a structured line.
+func (tf *TimeFinder) findFieldTimestamp(fields Fields) (time.Time, error) {
+	text, ok := fields.Field(tf.timeField)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no '%s' field", tf.timeField)
+	}
+	return tf.findFirstTimestamp(text)
+}
+
+// findTimestamp returns the first timestamp in the line along with the text that it was parsed from.
+func (tf *TimeFinder) findTimestamp(s string) (string, time.Time, error) {
+	if tf.epoch != nil {
+		return findEpochTimestamp(s, *tf.epoch, tf.timeRegex, tf.epochLatest)
+	}
+
 	if dateString := tf.timeRegex.FindString(s); dateString != "" {
-		return time.Parse(tf.timeFormat, dateString)
+		t, err := tf.parse(dateString)
+		return dateString, t, err
 	}
 
-	return time.Time{}, fmt.Errorf("couldn't find time in line '%s'", s)
+	return "", time.Time{}, fmt.Errorf("couldn't find time in line '%s'", s)
+}
+
+func (tf *TimeFinder) parse(dateString string) (time.Time, error) {
+	location := tf.location
+	if location == nil {
+		location = time.UTC
+	}
+	t, err := time.ParseInLocation(tf.timeFormat, dateString, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if tf.yearless {
+		t = tf.years.apply(t)
+	}
+	if !tf.hasOffset {
+		t = tf.zones.apply(t)
+	}
+	return t, nil
 }
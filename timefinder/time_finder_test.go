@@ -44,6 +44,20 @@ func Test_checkDateFormatForErrors(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "for date value without a year, does not return an error",
+			args: args{
+				dateFormat: "Jan _2 15:04:05",
+			},
+			wantErr: false,
+		},
+		{
+			name: "for date value without a day, returns an error",
+			args: args{
+				dateFormat: "2006 15:04:05",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -118,7 +132,7 @@ func TestTimeFinder_extractTimestampFromEachLine(t *testing.T) {
 			args: args{
 				r: strings.NewReader(sampleLogLine),
 			},
-			want: []int64{parseTime(sampleApacheCommonLogFormatTimestamp).Unix()},
+			want: []int64{parseTime(sampleApacheCommonLogFormatTimestamp).UnixNano()},
 		},
 		{
 			name:   "for two lines, returns two timestamps",
@@ -126,7 +140,7 @@ func TestTimeFinder_extractTimestampFromEachLine(t *testing.T) {
 			args: args{
 				r: strings.NewReader(strings.Repeat(sampleLogLine+"\n", 2)),
 			},
-			want: test.RepeatTime(parseTime(sampleApacheCommonLogFormatTimestamp).Unix(), 2),
+			want: test.RepeatTime(parseTime(sampleApacheCommonLogFormatTimestamp).UnixNano(), 2),
 		},
 	}
 	for _, tt := range tests {
@@ -135,7 +149,10 @@ func TestTimeFinder_extractTimestampFromEachLine(t *testing.T) {
 				timeFormat: tt.fields.timeFormat,
 				timeRegex:  tt.fields.timeRegex,
 			}
-			got := tf.ExtractTimestampFromEachLine(tt.args.r)
+			got, err := tf.ExtractTimestampFromEachLine(tt.args.r)
+			if err != nil {
+				t.Fatalf("ExtractTimestampFromEachLine() error = %v", err)
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("extractTimestampFromEachLine() got = %v, want %v", got, tt.want)
 			}
@@ -244,3 +261,57 @@ func Benchmark_findFirstTimestamp(b *testing.B) {
 		tf.findFirstTimestamp(sampleLogLine)
 	}
 }
+
+func TestTimeFinder_SetTimeRange(t *testing.T) {
+	tf, err := NewTimeFinder(apacheCommonLogFormatDate)
+	if err != nil {
+		t.Fatalf("unexpected NewTimeFinder() error = %v", err)
+	}
+	tf.SetTimeRange(TimeRange{
+		Since: parseTime("23/Nov/2019:06:26:41.000"),
+		Until: parseTime("23/Nov/2019:06:26:42.000"),
+	})
+
+	lines := "[23/Nov/2019:06:26:40.000]\n[23/Nov/2019:06:26:41.000]\n[23/Nov/2019:06:26:42.000]\n[23/Nov/2019:06:26:43.000]\n"
+	got, err := tf.ExtractTimestampFromEachLine(strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("ExtractTimestampFromEachLine() error = %v", err)
+	}
+	want := []int64{parseTime("23/Nov/2019:06:26:41.000").UnixNano(), parseTime("23/Nov/2019:06:26:42.000").UnixNano()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractTimestampFromEachLine() got = %v, want %v", got, want)
+	}
+}
+
+func TestTimeFinder_ForEachTimestampStats(t *testing.T) {
+	tf, err := NewTimeFinder(apacheCommonLogFormatDate)
+	if err != nil {
+		t.Fatalf("NewTimeFinder() error = %v", err)
+	}
+	tf.SetTimeRange(TimeRange{Since: parseTime("23/Nov/2019:06:26:41.000")})
+
+	lines := "no timestamp\n[23/Nov/2019:06:26:40.000]\n[23/Nov/2019:06:26:41.000]\n" + strings.Repeat("x", 300) + "\n\n[23/Nov/2019:06:26:42.000]"
+	stats, err := tf.ForEachTimestamp(strings.NewReader(lines), func(int64) {})
+	if err != nil {
+		t.Fatalf("ForEachTimestamp() error = %v", err)
+	}
+	want := ScanStats{
+		Lines:            6,
+		Matched:          2,
+		Unmatched:        3,
+		UnmatchedSamples: []string{"no timestamp", strings.Repeat("x", maxUnmatchedSampleLength), ""},
+		OutOfRange:       1,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("ForEachTimestamp() stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestScanStats_Add(t *testing.T) {
+	stats := ScanStats{Lines: 3, Matched: 1, Unmatched: 2, UnmatchedSamples: []string{"a", "b"}}
+	stats.Add(ScanStats{Lines: 6, Matched: 1, Unmatched: 4, UnmatchedSamples: []string{"c", "d", "e", "f"}, OutOfRange: 1, LongLines: 1})
+	want := ScanStats{Lines: 9, Matched: 2, Unmatched: 6, UnmatchedSamples: []string{"a", "b", "c", "d", "e"}, OutOfRange: 1, LongLines: 1}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Add() = %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,61 @@
+package timefinder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeRange limits the timestamps that a TimeFinder reports. A zero Since or Until leaves that end of the range open.
+type TimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Contains reports whether t falls within the range. Both ends of the range are inclusive.
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && t.After(r.Until) {
+		return false
+	}
+	return true
+}
+
+// ParseRangeBound parses one end of a time range. It accepts relative durations like "2h" or "-30m", which are
+// measured back from now (a leading "+" measures forward instead), RFC3339 timestamps, and timestamps in the
+// TimeFinder's format. Timestamps without a UTC offset are in the TimeFinder's location. If the format doesn't include
+// the year, it's inferred like the year of the first timestamp in the log, from the year or reference time that the
+// TimeFinder was given.
+func (tf *TimeFinder) ParseRangeBound(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")); err == nil {
+		if strings.HasPrefix(s, "+") {
+			return now.Add(d), nil
+		}
+		return now.Add(-d), nil
+	}
+
+	layout := tf.timeFormat
+	location := tf.location
+	if location == nil {
+		location = time.UTC
+	}
+	if format, ok := epochFormats[layout]; ok {
+		if t, err := parseEpoch(s, format.unit); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, l := range []string{time.RFC3339Nano, layout} {
+		if t, err := time.ParseInLocation(l, s, location); err == nil {
+			if t.Year() == 0 {
+				years := yearInference{year: tf.years.year, reference: tf.years.reference}
+				t = years.apply(t)
+			}
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("couldn't parse '%s' as a duration, an RFC3339 timestamp, or a timestamp in the format '%s'", s, layout)
+}
@@ -0,0 +1,97 @@
+package timefinder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeRange_Contains(t *testing.T) {
+	since := parseTime("23/Nov/2019:06:00:00.000")
+	until := parseTime("23/Nov/2019:07:00:00.000")
+	tests := []struct {
+		name      string
+		timeRange TimeRange
+		t         time.Time
+		want      bool
+	}{
+		{"open range", TimeRange{}, since, true},
+		{"before since", TimeRange{Since: since}, since.Add(-time.Second), false},
+		{"at since", TimeRange{Since: since}, since, true},
+		{"at until", TimeRange{Until: until}, until, true},
+		{"after until", TimeRange{Until: until}, until.Add(time.Second), false},
+		{"within closed range", TimeRange{Since: since, Until: until}, since.Add(time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.timeRange.Contains(tt.t); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeFinder_ParseRangeBound(t *testing.T) {
+	now := parseTime("23/Nov/2019:12:00:00.000")
+	tests := []struct {
+		name    string
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{"bare duration", "2h", now.Add(-2 * time.Hour), false},
+		{"negative duration", "-30m", now.Add(-30 * time.Minute), false},
+		{"positive duration", "+30m", now.Add(30 * time.Minute), false},
+		{"RFC3339", "2019-11-23T06:26:40Z", parseTime("23/Nov/2019:06:26:40.000"), false},
+		{"RFC3339 with offset", "2019-11-23T07:26:40.5+01:00", parseTime("23/Nov/2019:06:26:40.500"), false},
+		{"log format", "23/Nov/2019:06:26:40.781", parseTime("23/Nov/2019:06:26:40.781"), false},
+		{"garbage", "yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := NewTimeFinder(apacheCommonLogFormatDate)
+			if err != nil {
+				t.Fatalf("NewTimeFinder() error = %v", err)
+			}
+			got, err := tf.ParseRangeBound(tt.s, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRangeBound() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseRangeBound() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("for a layout without a year, infers the year like the TimeFinder does", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			year      int
+			reference time.Time
+			want      time.Time
+		}{
+			{"from the reference time", 0, now, time.Date(2018, time.December, 31, 23, 0, 0, 0, time.UTC)},
+			{"from the year", 2016, now, time.Date(2016, time.December, 31, 23, 0, 0, 0, time.UTC)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tf, err := NewTimeFinder("Jan _2 15:04:05")
+				if err != nil {
+					t.Fatalf("NewTimeFinder() error = %v", err)
+				}
+				tf.SetReferenceTime(tt.reference)
+				if tt.year != 0 {
+					tf.SetYear(tt.year)
+				}
+				// now doesn't matter when the log was written
+				got, err := tf.ParseRangeBound("Dec 31 23:00:00", now.AddDate(5, 0, 0))
+				if err != nil {
+					t.Fatalf("ParseRangeBound() error = %v", err)
+				}
+				if !got.Equal(tt.want) {
+					t.Errorf("ParseRangeBound() got = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+}
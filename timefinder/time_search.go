@@ -0,0 +1,165 @@
+package timefinder
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"time"
+)
+
+// ErrNotTimeOrdered is returned by FindTimeRangeOffsets when the timestamps in the input don't appear to be in
+// chronological order, which means that it can't be searched.
+var ErrNotTimeOrdered = errors.New("timestamps are not in chronological order")
+
+// ErrYearlessFormat is returned by FindTimeRangeOffsets when the time format doesn't include the year. The year of
+// each timestamp depends on the timestamps before it, so the input must be scanned from the beginning.
+var ErrYearlessFormat = errors.New("timestamps without a year can't be searched")
+
+const (
+	// orderSampleCount is the number of evenly spaced points that are checked to make sure that the input is in
+	// chronological order before searching it.
+	orderSampleCount = 16
+	// maxProbeLines is the number of lines that are read when looking for a timestamp near a point in the input. If
+	// none of them has a timestamp, the point is treated as though it's past the end of the input.
+	maxProbeLines = 1000
+)
+
+// probe describes the first line with a timestamp at or after some offset in the input.
+type probe struct {
+	lineStart int64
+	lineEnd   int64
+	timestamp time.Time
+	found     bool
+}
+
+// FindTimeRangeOffsets uses binary search to find the section of the input that contains the lines within the time
+// range. It returns the offset of the first line in the range and the offset just past the last line. The input must
+// be in chronological order; if it isn't, ErrNotTimeOrdered is returned and the caller should scan the whole input
+// instead.
+func (tf *TimeFinder) FindTimeRangeOffsets(r io.ReaderAt, size int64) (int64, int64, error) {
+	if tf.yearless {
+		return 0, 0, ErrYearlessFormat
+	}
+
+	var probes []probe
+	probeAt := func(offset int64) (probe, error) {
+		p, err := tf.probe(r, size, offset)
+		if err == nil && p.found {
+			probes = append(probes, p)
+		}
+		return p, err
+	}
+
+	for i := int64(0); i < orderSampleCount; i++ {
+		if _, err := probeAt(size * i / orderSampleCount); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	start, end := int64(0), size
+	var err error
+	if !tf.timeRange.Since.IsZero() {
+		start, err = searchOffsets(size, probeAt, func(t time.Time) bool {
+			return !t.Before(tf.timeRange.Since)
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if !tf.timeRange.Until.IsZero() {
+		end, err = searchOffsets(size, probeAt, func(t time.Time) bool {
+			return t.After(tf.timeRange.Until)
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// Every probe, including those made during the search, must agree that the input is in chronological order.
+	// Otherwise, the search may have been misled.
+	sort.Slice(probes, func(i, j int) bool {
+		return probes[i].lineStart < probes[j].lineStart
+	})
+	for i := 1; i < len(probes); i++ {
+		if probes[i].timestamp.Before(probes[i-1].timestamp) {
+			return 0, 0, ErrNotTimeOrdered
+		}
+	}
+
+	if end < start {
+		end = start
+	}
+	return start, end, nil
+}
+
+// searchOffsets returns the start of the first line whose timestamp satisfies isAtOrPast, or the size of the input if
+// there is no such line.
+func searchOffsets(size int64, probeAt func(int64) (probe, error), isAtOrPast func(time.Time) bool) (int64, error) {
+	// Invariant: every line that starts before lo has a timestamp that doesn't satisfy isAtOrPast (or no timestamp),
+	// and the first line with a timestamp at or after hi satisfies it (or there isn't one).
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		p, err := probeAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if !p.found || isAtOrPast(p.timestamp) {
+			hi = mid
+		} else {
+			lo = p.lineEnd
+		}
+	}
+	return lo, nil
+}
+
+// probe finds the first line with a timestamp that starts at or after offset.
+func (tf *TimeFinder) probe(r io.ReaderAt, size int64, offset int64) (probe, error) {
+	// Probes jump around the input, so earlier probes can't help to place timestamps in a repeated hour at the end of
+	// daylight saving time. If one is placed in the wrong occurrence, the order check will notice.
+	tf.zones.last = time.Time{}
+	defer func() { tf.zones.last = time.Time{} }()
+
+	br := bufio.NewReader(io.NewSectionReader(r, offset, size-offset))
+	lineStart := offset
+
+	if offset > 0 {
+		// Unless we happen to be at the start of a line, skip ahead to the next one
+		var previous [1]byte
+		if _, err := r.ReadAt(previous[:], offset-1); err != nil {
+			return probe{}, err
+		}
+		if previous[0] != '\n' {
+			partialLine, err := br.ReadString('\n')
+			lineStart += int64(len(partialLine))
+			if err == io.EOF {
+				return probe{}, nil
+			} else if err != nil {
+				return probe{}, err
+			}
+		}
+	}
+
+	for i := 0; i < maxProbeLines; i++ {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if t, parseErr := tf.findLineTimestamp(line); parseErr == nil {
+				return probe{
+					lineStart: lineStart,
+					lineEnd:   lineStart + int64(len(line)),
+					timestamp: t,
+					found:     true,
+				}, nil
+			}
+		}
+		lineStart += int64(len(line))
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return probe{}, err
+		}
+	}
+
+	return probe{}, nil
+}
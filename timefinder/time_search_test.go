@@ -0,0 +1,80 @@
+package timefinder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timeOrderedLog returns a log with one line per second, starting at 06:00:00, with an occasional line that has no
+// timestamp. It also returns, for each timestamped line, the offset just past the timestamped line before it.
+func timeOrderedLog(lineCount int) (string, []int64) {
+	var log strings.Builder
+	offsets := make([]int64, 0, lineCount)
+	start := parseTime("23/Nov/2019:06:00:00.000")
+	for i := 0; i < lineCount; i++ {
+		offsets = append(offsets, int64(log.Len()))
+		if i%7 == 3 {
+			log.WriteString("    continuation line without a timestamp\n")
+		}
+		fmt.Fprintf(&log, "10.1.1.%d [%s] GET /%d\n", i%256, start.Add(time.Duration(i)*time.Second).Format(apacheCommonLogFormatDate), i)
+	}
+	return log.String(), offsets
+}
+
+func TestTimeFinder_FindTimeRangeOffsets(t *testing.T) {
+	log, offsets := timeOrderedLog(5000)
+	start := parseTime("23/Nov/2019:06:00:00.000")
+	size := int64(len(log))
+
+	tests := []struct {
+		name      string
+		timeRange TimeRange
+		wantStart int64
+		wantEnd   int64
+	}{
+		{"open range", TimeRange{}, 0, size},
+		{"since only", TimeRange{Since: start.Add(1234 * time.Second)}, offsets[1234], size},
+		{"until only", TimeRange{Until: start.Add(4321 * time.Second)}, 0, offsets[4322]},
+		{"closed range", TimeRange{Since: start.Add(10 * time.Second), Until: start.Add(20 * time.Second)}, offsets[10], offsets[21]},
+		{"range before the log", TimeRange{Until: start.Add(-time.Hour)}, 0, 0},
+		{"range after the log", TimeRange{Since: start.Add(24 * time.Hour)}, size, size},
+		{"since between timestamps", TimeRange{Since: start.Add(1500 * time.Millisecond)}, offsets[2], size},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, _ := NewTimeFinder(apacheCommonLogFormatDate)
+			tf.SetTimeRange(tt.timeRange)
+			gotStart, gotEnd, err := tf.FindTimeRangeOffsets(strings.NewReader(log), size)
+			if err != nil {
+				t.Fatalf("FindTimeRangeOffsets() error = %v", err)
+			}
+			if gotStart != tt.wantStart || gotEnd != tt.wantEnd {
+				t.Errorf("FindTimeRangeOffsets() = (%d, %d), want (%d, %d)", gotStart, gotEnd, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+
+	t.Run("for input that isn't in chronological order, returns ErrNotTimeOrdered", func(t *testing.T) {
+		lines := strings.SplitAfter(log, "\n")
+		lines[len(lines)/2], lines[len(lines)/4] = lines[len(lines)/4], lines[len(lines)/2]
+		shuffled := strings.Join(lines[len(lines)/2:], "") + strings.Join(lines[:len(lines)/2], "")
+
+		tf, _ := NewTimeFinder(apacheCommonLogFormatDate)
+		tf.SetTimeRange(TimeRange{Since: start.Add(time.Hour)})
+		if _, _, err := tf.FindTimeRangeOffsets(strings.NewReader(shuffled), size); err != ErrNotTimeOrdered {
+			t.Errorf("FindTimeRangeOffsets() error = %v, want %v", err, ErrNotTimeOrdered)
+		}
+	})
+}
+
+func Benchmark_FindTimeRangeOffsets(b *testing.B) {
+	log, _ := timeOrderedLog(100000)
+	tf, _ := NewTimeFinder(apacheCommonLogFormatDate)
+	tf.SetTimeRange(TimeRange{Since: parseTime("23/Nov/2019:06:30:00.000"), Until: parseTime("23/Nov/2019:07:00:00.000")})
+	r := strings.NewReader(log)
+	for i := 0; i < b.N; i++ {
+		tf.FindTimeRangeOffsets(r, int64(len(log)))
+	}
+}
@@ -0,0 +1,49 @@
+package timefinder
+
+import "time"
+
+const (
+	// referenceSlack allows yearless timestamps to be a little later than the reference time, e.g. because the log
+	// was written in a different time zone, without being pushed back to the previous year.
+	referenceSlack = 24 * time.Hour
+	// rolloverThreshold is how far a yearless timestamp can jump backward (or forward) from the previous one before
+	// it's assumed to belong to the next (or previous) year.
+	rolloverThreshold = 183 * 24 * time.Hour
+)
+
+// yearInference fills in the year of timestamps whose format doesn't include one, such as classic syslog timestamps.
+// The first timestamp gets either an explicit year or the latest year that doesn't put it after the reference time.
+// Each timestamp after that gets the year that keeps it closest to the one before it, which handles logs that cross
+// from December into January.
+type yearInference struct {
+	year      int
+	reference time.Time
+	last      time.Time
+}
+
+func (y *yearInference) apply(t time.Time) time.Time {
+	var withYear time.Time
+	switch {
+	case !y.last.IsZero():
+		withYear = setYear(t, y.last.Year())
+		if withYear.Before(y.last.Add(-rolloverThreshold)) {
+			withYear = setYear(t, y.last.Year()+1)
+		} else if withYear.After(y.last.Add(rolloverThreshold)) {
+			withYear = setYear(t, y.last.Year()-1)
+		}
+	case y.year != 0:
+		withYear = setYear(t, y.year)
+	default:
+		withYear = setYear(t, y.reference.Year())
+		if withYear.After(y.reference.Add(referenceSlack)) {
+			withYear = setYear(t, y.reference.Year()-1)
+		}
+	}
+
+	y.last = withYear
+	return withYear
+}
+
+func setYear(t time.Time, year int) time.Time {
+	return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
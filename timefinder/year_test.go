@@ -0,0 +1,92 @@
+package timefinder
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_yearInference(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name      string
+		years     yearInference
+		yearless  []time.Time
+		wantYears []int
+	}{
+		{
+			name:      "uses the year of the reference time",
+			years:     yearInference{reference: date(2019, time.November, 30)},
+			yearless:  []time.Time{date(0, time.November, 23), date(0, time.November, 24)},
+			wantYears: []int{2019, 2019},
+		},
+		{
+			name:      "uses the previous year if the timestamp would be after the reference time",
+			years:     yearInference{reference: date(2020, time.January, 2)},
+			yearless:  []time.Time{date(0, time.November, 23)},
+			wantYears: []int{2019},
+		},
+		{
+			name:      "allows timestamps that are slightly after the reference time",
+			years:     yearInference{reference: date(2019, time.November, 23).Add(-time.Hour)},
+			yearless:  []time.Time{date(0, time.November, 23)},
+			wantYears: []int{2019},
+		},
+		{
+			name:      "rolls over from December to January",
+			years:     yearInference{reference: date(2020, time.January, 2)},
+			yearless:  []time.Time{date(0, time.December, 30), date(0, time.December, 31), date(0, time.January, 1)},
+			wantYears: []int{2019, 2019, 2020},
+		},
+		{
+			name:      "rolls back from January to December",
+			years:     yearInference{reference: date(2020, time.January, 2)},
+			yearless:  []time.Time{date(0, time.January, 1), date(0, time.December, 31)},
+			wantYears: []int{2020, 2019},
+		},
+		{
+			name:      "explicit year takes precedence over the reference time",
+			years:     yearInference{year: 2015, reference: date(2020, time.January, 2)},
+			yearless:  []time.Time{date(0, time.December, 31), date(0, time.January, 1)},
+			wantYears: []int{2015, 2016},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, yearless := range tt.yearless {
+				got := tt.years.apply(yearless)
+				want := setYear(yearless, tt.wantYears[i])
+				if !got.Equal(want) {
+					t.Errorf("apply(%v) = %v, want %v", yearless, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeFinder_yearlessFormat(t *testing.T) {
+	tf, err := NewTimeFinder("Jan _2 15:04:05")
+	if err != nil {
+		t.Fatalf("unexpected NewTimeFinder() error = %v", err)
+	}
+	tf.SetReferenceTime(time.Date(2020, time.January, 1, 0, 0, 5, 0, time.UTC))
+
+	lines := "Dec 31 23:59:59 myhost app: last of the year\nJan  1 00:00:01 myhost app: first of the year\n"
+	got, err := tf.ExtractTimestampFromEachLine(strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("ExtractTimestampFromEachLine() error = %v", err)
+	}
+	want := []int64{
+		time.Date(2019, time.December, 31, 23, 59, 59, 0, time.UTC).UnixNano(),
+		time.Date(2020, time.January, 1, 0, 0, 1, 0, time.UTC).UnixNano(),
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ExtractTimestampFromEachLine() got = %v, want %v", got, want)
+	}
+
+	if _, _, err := tf.FindTimeRangeOffsets(strings.NewReader(lines), int64(len(lines))); err != ErrYearlessFormat {
+		t.Errorf("FindTimeRangeOffsets() error = %v, want %v", err, ErrYearlessFormat)
+	}
+}